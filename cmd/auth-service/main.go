@@ -1,16 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
-	"github.com/gofiber/fiber/v2"
 	"github.com/joho/godotenv"
 
+	"auth-service/internal/breach"
+	"auth-service/internal/config"
 	"auth-service/internal/db"
-	"auth-service/internal/handlers"
-	"auth-service/internal/middleware"
+	"auth-service/internal/keystore"
+	"auth-service/internal/mailer"
+	"auth-service/internal/passwordhash"
+	"auth-service/internal/ratelimit"
+	"auth-service/internal/server"
+	jwtpkg "auth-service/pkg/jwt"
 )
 
 func main() {
@@ -19,46 +26,55 @@ func main() {
 		log.Println("⚠️  No .env file found, using system environment variables")
 	}
 
-	db.ConnectDB()
-	defer db.CloseDB()
+	jwtpkg.Configure(jwtpkg.Config{
+		Issuer:         config.Env("JWT_ISSUER", "auth-service"),
+		Audience:       config.Env("JWT_AUDIENCE", "auth-service"),
+		AccessTokenTTL: time.Duration(config.EnvInt("JWT_EXPIRY_HOURS", 1)) * time.Hour,
+		Leeway:         time.Duration(config.EnvInt("JWT_LEEWAY_SECONDS", 30)) * time.Second,
+	})
 
-	app := fiber.New()
+	// Password hashing algorithm, parameters and pepper
+	hasher, err := passwordhash.FromEnv()
+	if err != nil {
+		log.Fatalf("❌ Invalid password hashing configuration: %v", err)
+	}
+	passwordhash.SetDefault(hasher)
+	passwordhash.SetPool(passwordhash.PoolFromEnv())
 
-	app.Get("api/v1/health", func(c *fiber.Ctx) error {
-		dbStatus := "disconnected"
-		if db.CheckHealth() {
-			dbStatus = "connected"
-		}
+	db.ConnectDB()
+	defer db.CloseDB()
 
-		return c.JSON(fiber.Map{
-			"status": "ok",
-			"db":     dbStatus,
-		})
-	})
+	// Signing keys: env key plus any rotated keys stored in the database
+	if err := keystore.Reload(context.Background()); err != nil {
+		log.Fatalf("❌ Failed to load JWT signing keys: %v", err)
+	}
+	keystore.StartAutoReload(time.Minute)
 
-	app.Get("api/v1/version", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"service": "auth-service",
-			"version": "v0.1.0",
-		})
-	})
-	app.Post("/api/v1/login", handlers.Login)
-	app.Get("/api/v1/me", middleware.AuthRequired(), handlers.Me)
-	app.Post("/api/v1/register", handlers.Register)
+	// Outbound mail is queued in email_outbox and delivered with retries
+	m, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatalf("❌ Invalid mail configuration: %v", err)
+	}
+	mailer.SetMailer(m)
+	mailer.StartWorker(30 * time.Second)
 
-	app.Get("/api/v1/superadmin/policies", middleware.AuthRequired(), handlers.GetAllPolicies)
-	app.Get("/api/v1/superadmin/policies/:name", middleware.AuthRequired(), handlers.GetPolicyByName)
-	app.Post("/api/v1/superadmin/policies", middleware.AuthRequired(), handlers.UpsertPolicies)
+	// Rate limits are per replica unless they share the database
+	if config.Env("RATE_LIMIT_STORE", "memory") == "postgres" {
+		ratelimit.SetStore(ratelimit.NewPostgresStore(db.DB))
+		log.Println("✅ Rate limits stored in PostgreSQL")
+	}
 
-	app.Get("/api/v1/admin/roles", middleware.AuthRequired(), handlers.GetRoles)
-	app.Post("/api/v1/admin/roles", middleware.AuthRequired(), handlers.CreateRole)
-	app.Post("/api/v1/admin/assign-role", middleware.AuthRequired(), handlers.AssignRole)
-	app.Delete("/api/v1/admin/revoke-role", middleware.AuthRequired(), handlers.RevokeRole)
+	// Passwords found in a local breach corpus are rejected by the password policy
+	if path := config.Env("BREACHED_PASSWORDS_FILE", ""); path != "" {
+		checker, err := breach.Load(path)
+		if err != nil {
+			log.Fatalf("❌ Failed to load breached password list: %v", err)
+		}
+		breach.SetChecker(checker)
+		log.Printf("✅ Breached password list loaded from %s", path)
+	}
 
-	app.Get("/api/v1/admin/users", middleware.AuthRequired(), handlers.ListUsers)
-	app.Get("/api/v1/admin/users/:id", middleware.AuthRequired(), handlers.GetUserByID)
-	app.Patch("/api/v1/admin/users/:id/status", middleware.AuthRequired(), handlers.UpdateUserStatus)
-	app.Delete("/api/v1/admin/users/:id", middleware.AuthRequired(), handlers.DeleteUser)
+	app := server.NewApp()
 
 	// ----------------------------------------------------
 	// 6️⃣ Start Server
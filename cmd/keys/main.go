@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"auth-service/internal/db"
+	"auth-service/internal/keystore"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage:
+  keys list
+  keys generate [RS256|ES256|ES384|EdDSA] [activates_at RFC3339]
+  keys promote <kid>
+  keys retire <kid> [retires_at RFC3339]`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  No .env file found, using system environment variables")
+	}
+
+	db.ConnectDB()
+	defer db.CloseDB()
+
+	ctx := context.Background()
+	args := os.Args[2:]
+
+	switch os.Args[1] {
+	case "list":
+		keys, err := keystore.List(ctx)
+		if err != nil {
+			log.Fatalf("❌ Failed to list signing keys: %v", err)
+		}
+		for _, k := range keys {
+			retires := "-"
+			if k.RetiresAt != nil {
+				retires = k.RetiresAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%-44s %-6s %-8s activates=%s retires=%s\n",
+				k.KID, k.Algorithm, k.Status, k.ActivatesAt.Format(time.RFC3339), retires)
+		}
+
+	case "generate":
+		alg := "ES256"
+		if len(args) > 0 {
+			alg = args[0]
+		}
+		activatesAt := time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+		if len(args) > 1 {
+			activatesAt = parseTime(args[1])
+		}
+		key, err := keystore.Generate(ctx, alg, activatesAt)
+		if err != nil {
+			log.Fatalf("❌ Failed to generate signing key: %v", err)
+		}
+		log.Printf("✅ Generated %s signing key %s (%s)", key.Algorithm, key.KID, key.Status)
+
+	case "promote":
+		if len(args) < 1 {
+			log.Fatal(usage)
+		}
+		if err := keystore.Promote(ctx, args[0]); err != nil {
+			log.Fatalf("❌ Failed to promote signing key: %v", err)
+		}
+		log.Printf("✅ Promoted signing key %s", args[0])
+
+	case "retire":
+		if len(args) < 1 {
+			log.Fatal(usage)
+		}
+		retiresAt := time.Now()
+		if len(args) > 1 {
+			retiresAt = parseTime(args[1])
+		}
+		if err := keystore.Retire(ctx, args[0], retiresAt); err != nil {
+			log.Fatalf("❌ Failed to retire signing key: %v", err)
+		}
+		log.Printf("✅ Signing key %s retires at %s", args[0], retiresAt.Format(time.RFC3339))
+
+	default:
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+}
+
+func parseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		log.Fatalf("❌ Invalid time %q, expected RFC3339: %v", s, err)
+	}
+	return t
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 
 	"auth-service/internal/db"
+	"auth-service/internal/passwordhash"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +17,13 @@ func main() {
 		log.Println("⚠️  No .env file found, using system environment variables")
 	}
 
+	// Password hashing algorithm, parameters and pepper
+	hasher, err := passwordhash.FromEnv()
+	if err != nil {
+		log.Fatalf("❌ Invalid password hashing configuration: %v", err)
+	}
+	passwordhash.SetDefault(hasher)
+
 	// Connect to DB
 	db.ConnectDB()
 	defer db.CloseDB()
@@ -0,0 +1,24 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"auth-service/internal/db"
+)
+
+// Record appends an entry to audit_logs. metadata may be nil.
+func Record(ctx context.Context, userID int, action string, metadata map[string]any) error {
+	var meta []byte
+	if metadata != nil {
+		var err error
+		if meta, err = json.Marshal(metadata); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.DB.Exec(ctx,
+		"INSERT INTO audit_logs (user_id, action, metadata) VALUES ($1, $2, $3);",
+		userID, action, meta)
+	return err
+}
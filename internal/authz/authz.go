@@ -0,0 +1,140 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"auth-service/internal/db"
+	"auth-service/internal/roles"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// cacheTTL bounds how long a decision may be served after a change made on
+// another replica; local changes invalidate the cache immediately
+const cacheTTL = 15 * time.Second
+
+// maxCacheEntries caps the decision cache; callers choose the action and
+// resource, so the number of distinct keys is otherwise unbounded
+const maxCacheEntries = 10000
+
+// Decision is the outcome of a single authorization check
+type Decision struct {
+	Action     string `json:"action"`
+	Resource   string `json:"resource"`
+	Permission string `json:"permission"`
+	Allowed    bool   `json:"allowed"`
+	Reason     string `json:"reason"`
+}
+
+type cacheKey struct {
+	userID     int
+	permission string
+}
+
+type cacheEntry struct {
+	decision Decision
+	expires  time.Time
+}
+
+var (
+	cacheMu sync.RWMutex
+	cache   = map[cacheKey]cacheEntry{}
+)
+
+// PermissionFor maps an action on a resource to the permission it requires.
+// The resource type is the part before the first "/" ("invoices/42" → "invoices").
+func PermissionFor(action, resource string) string {
+	resourceType, _, _ := strings.Cut(resource, "/")
+	return resourceType + ":" + action
+}
+
+// Decide evaluates whether a user may perform action on resource using the
+// user's current account status, roles and permissions from the database
+func Decide(ctx context.Context, userID int, action, resource string) (Decision, error) {
+	d := Decision{Action: action, Resource: resource, Permission: PermissionFor(action, resource)}
+	if action == "" || resource == "" {
+		d.Reason = "action and resource are required"
+		return d, nil
+	}
+
+	key := cacheKey{userID: userID, permission: d.Permission}
+	cacheMu.RLock()
+	entry, ok := cache[key]
+	cacheMu.RUnlock()
+	if ok && time.Now().Before(entry.expires) {
+		cached := entry.decision
+		cached.Action, cached.Resource = action, resource
+		return cached, nil
+	}
+
+	var isActive bool
+	err := db.DB.QueryRow(ctx, "SELECT is_active FROM users WHERE id=$1;", userID).Scan(&isActive)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		d.Reason = "user not found"
+	case err != nil:
+		return d, err
+	case !isActive:
+		d.Reason = "user is inactive"
+	default:
+		perms, err := roles.UserPermissions(ctx, userID)
+		if err != nil {
+			return d, err
+		}
+		d.Allowed = roles.HasPermission(perms, d.Permission)
+		if d.Allowed {
+			d.Reason = fmt.Sprintf("granted by permission %s", d.Permission)
+		} else {
+			d.Reason = fmt.Sprintf("missing permission %s", d.Permission)
+		}
+	}
+
+	storeDecision(key, d, time.Now())
+	return d, nil
+}
+
+// storeDecision caches a decision. When the cache is full, expired entries
+// are swept first; if that is not enough, arbitrary entries are dropped.
+func storeDecision(key cacheKey, d Decision, now time.Time) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	if _, ok := cache[key]; !ok && len(cache) >= maxCacheEntries {
+		for k, e := range cache {
+			if !now.Before(e.expires) {
+				delete(cache, k)
+			}
+		}
+		// Map iteration order is random, so this evicts a random tenth
+		for k := range cache {
+			if len(cache) < maxCacheEntries*9/10 {
+				break
+			}
+			delete(cache, k)
+		}
+	}
+	cache[key] = cacheEntry{decision: d, expires: now.Add(cacheTTL)}
+}
+
+// InvalidateUser drops cached decisions for one user (role assigned / revoked)
+func InvalidateUser(userID int) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	for key := range cache {
+		if key.userID == userID {
+			delete(cache, key)
+		}
+	}
+}
+
+// InvalidateAll drops every cached decision (policy, grant or hierarchy change)
+func InvalidateAll() {
+	cacheMu.Lock()
+	cache = map[cacheKey]cacheEntry{}
+	cacheMu.Unlock()
+}
@@ -0,0 +1,49 @@
+package authz
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPermissionFor(t *testing.T) {
+	cases := map[[2]string]string{
+		{"read", "invoices/42"}:  "invoices:read",
+		{"read", "invoices"}:     "invoices:read",
+		{"write", "users/7/mfa"}: "users:write",
+	}
+	for in, want := range cases {
+		if got := PermissionFor(in[0], in[1]); got != want {
+			t.Errorf("PermissionFor(%q, %q) = %q, want %q", in[0], in[1], got, want)
+		}
+	}
+}
+
+func TestCacheIsBounded(t *testing.T) {
+	InvalidateAll()
+	t.Cleanup(InvalidateAll)
+	now := time.Now()
+
+	for i := range 3 * maxCacheEntries {
+		storeDecision(cacheKey{userID: 1, permission: fmt.Sprintf("p%d:read", i)}, Decision{}, now)
+	}
+	if len(cache) > maxCacheEntries {
+		t.Fatalf("cache holds %d entries, cap is %d", len(cache), maxCacheEntries)
+	}
+
+	// Expired entries are swept before live ones are evicted
+	InvalidateAll()
+	for i := range maxCacheEntries {
+		storeDecision(cacheKey{userID: 1, permission: fmt.Sprintf("p%d:read", i)}, Decision{}, now)
+	}
+	later := now.Add(cacheTTL)
+	live := cacheKey{userID: 2, permission: "users:read"}
+	storeDecision(live, Decision{Allowed: true}, later)
+	storeDecision(cacheKey{userID: 2, permission: "users:write"}, Decision{}, later)
+	if len(cache) != 2 {
+		t.Fatalf("cache holds %d entries after expiry, want 2", len(cache))
+	}
+	if !cache[live].decision.Allowed {
+		t.Fatal("live entry was evicted")
+	}
+}
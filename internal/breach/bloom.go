@@ -0,0 +1,60 @@
+package breach
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// falsePositiveRate rejects about one good password in a thousand, at roughly
+// 1.8 bytes per listed password
+const falsePositiveRate = 0.001
+
+// BloomFilter is a compact set that can return false positives but never
+// false negatives
+type BloomFilter struct {
+	bits   []uint64
+	m      uint64 // number of bits
+	hashes uint64 // number of bit positions per entry
+}
+
+// NewBloomFilter sizes a filter for n entries at false positive rate p
+func NewBloomFilter(n int, p float64) *BloomFilter {
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	return &BloomFilter{
+		bits:   make([]uint64, (m+63)/64),
+		m:      m,
+		hashes: max(k, 1),
+	}
+}
+
+// Add inserts a password
+func (b *BloomFilter) Add(password string) {
+	h1, h2 := hashPair(password)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains reports whether a password was probably added
+func (b *BloomFilter) Contains(password string) bool {
+	h1, h2 := hashPair(password)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashPair derives the two base hashes for double hashing from FNV-1a/128
+func hashPair(s string) (uint64, uint64) {
+	h := fnv.New128a()
+	h.Write([]byte(s))
+	sum := h.Sum(nil)
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
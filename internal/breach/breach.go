@@ -0,0 +1,115 @@
+package breach
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Checker reports whether a password appears in a breach corpus
+type Checker interface {
+	Contains(password string) bool
+}
+
+var (
+	mu      sync.RWMutex
+	current Checker
+)
+
+// SetChecker installs the corpus used by Contains; nil disables screening
+func SetChecker(c Checker) {
+	mu.Lock()
+	current = c
+	mu.Unlock()
+}
+
+// Contains checks a password against the installed corpus. Without one,
+// nothing is considered breached.
+func Contains(password string) bool {
+	mu.RLock()
+	c := current
+	mu.RUnlock()
+	return c != nil && c.Contains(password)
+}
+
+// Load opens a breach list and picks its format from the first line:
+//   - "<40 hex chars>[:count]" lines sorted by hash, as in the Have I Been Pwned
+//     SHA-1 download, are binary-searched on disk
+//   - anything else is a plain list with one password per line, loaded into
+//     a bloom filter
+func Load(path string) (Checker, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && first == "" {
+		f.Close()
+		return nil, fmt.Errorf("breach list %s is empty", path)
+	}
+
+	if isHashLine(strings.TrimRight(first, "\r\n")) {
+		return newHashFile(f)
+	}
+	defer f.Close()
+
+	// The sniffing reader buffered ahead; count from the start of the file
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return loadPlainList(f)
+}
+
+// isHashLine reports whether a line looks like "<sha1 hex>" or "<sha1 hex>:<count>"
+func isHashLine(line string) bool {
+	if len(line) < 40 || (len(line) > 40 && line[40] != ':') {
+		return false
+	}
+	for _, c := range line[:40] {
+		if !isHex(byte(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// loadPlainList counts the entries, sizes a bloom filter for them and fills it
+func loadPlainList(f *os.File) (Checker, error) {
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.TrimRight(scanner.Text(), "\r") != "" {
+			count++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.New("breach list has no entries")
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	filter := NewBloomFilter(count, falsePositiveRate)
+	scanner = bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			filter.Add(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
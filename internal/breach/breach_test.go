@@ -0,0 +1,195 @@
+package breach
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeList(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// hashList builds a sorted HIBP-style file for the given passwords
+func hashList(passwords []string, format func(hash string, i int) string, eol string, trailing bool) string {
+	hashes := make([]string, len(passwords))
+	for i, p := range passwords {
+		hashes[i] = sha1Hex(p)
+	}
+	slices.Sort(hashes)
+	lines := make([]string, len(hashes))
+	for i, h := range hashes {
+		lines[i] = format(h, i)
+	}
+	content := strings.Join(lines, eol)
+	if trailing {
+		content += eol
+	}
+	return content
+}
+
+func TestHashFile(t *testing.T) {
+	var passwords []string
+	for i := range 500 {
+		passwords = append(passwords, fmt.Sprintf("password-%d", i))
+	}
+	hashes := make([]string, len(passwords))
+	for i, p := range passwords {
+		hashes[i] = sha1Hex(p)
+	}
+	slices.Sort(hashes)
+	byHash := map[string]string{}
+	for _, p := range passwords {
+		byHash[sha1Hex(p)] = p
+	}
+	first, last := byHash[hashes[0]], byHash[hashes[len(hashes)-1]]
+
+	withCount := func(h string, i int) string { return fmt.Sprintf("%s:%d", h, i+1) }
+	bare := func(h string, _ int) string { return h }
+	lower := func(h string, i int) string { return strings.ToLower(h) + ":" + fmt.Sprint(i) }
+
+	cases := []struct {
+		name    string
+		content string
+	}{
+		{"counts, LF", hashList(passwords, withCount, "\n", true)},
+		{"no trailing newline", hashList(passwords, withCount, "\n", false)},
+		{"CRLF", hashList(passwords, withCount, "\r\n", true)},
+		{"CRLF, no trailing newline", hashList(passwords, withCount, "\r\n", false)},
+		{"hashes only", hashList(passwords, bare, "\n", false)},
+		{"lowercase hex", hashList(passwords, lower, "\n", true)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker, err := Load(writeList(t, tc.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := checker.(*hashFile); !ok {
+				t.Fatalf("loaded as %T, want a hash file", checker)
+			}
+			for _, p := range []string{first, last, passwords[0], passwords[250], passwords[499]} {
+				if !checker.Contains(p) {
+					t.Errorf("Contains(%q) = false", p)
+				}
+			}
+			for _, p := range []string{"", "not-listed", "password-500", "PASSWORD-1"} {
+				if checker.Contains(p) {
+					t.Errorf("Contains(%q) = true", p)
+				}
+			}
+		})
+	}
+
+	t.Run("single line", func(t *testing.T) {
+		checker, err := Load(writeList(t, sha1Hex("hunter2")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !checker.Contains("hunter2") || checker.Contains("hunter3") {
+			t.Fatal("wrong answer for a one-line file")
+		}
+	})
+}
+
+func TestPlainList(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		listed  []string
+	}{
+		{"short list", "123456\npassword\nqwerty\nletmein\n", []string{"123456", "password", "qwerty", "letmein"}},
+		{"no trailing newline", "123456\nletmein", []string{"123456", "letmein"}},
+		{"CRLF and blank lines", "123456\r\n\r\nletmein\r\n", []string{"123456", "letmein"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker, err := Load(writeList(t, tc.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range tc.listed {
+				if !checker.Contains(p) {
+					t.Errorf("Contains(%q) = false", p)
+				}
+			}
+		})
+	}
+
+	if _, err := Load(writeList(t, "")); err == nil {
+		t.Error("empty file loaded")
+	}
+	if _, err := Load(writeList(t, "\n\n")); err == nil {
+		t.Error("file without entries loaded")
+	}
+}
+
+// A list larger than the sniffing buffer must still be counted in full, or
+// the filter is undersized and its false positive rate climbs
+func TestPlainListSizing(t *testing.T) {
+	const n = 2000
+	var b strings.Builder
+	for i := range n {
+		fmt.Fprintf(&b, "listed-%d\n", i)
+	}
+	checker, err := Load(writeList(t, b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := checker.(*BloomFilter)
+	if want := NewBloomFilter(n, falsePositiveRate); filter.m != want.m {
+		t.Fatalf("filter has %d bits, want %d (sized for %d entries)", filter.m, want.m, n)
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	const n = 10000
+	filter := NewBloomFilter(n, falsePositiveRate)
+	for i := range n {
+		filter.Add(fmt.Sprintf("in-%d", i))
+	}
+	for i := range n {
+		if p := fmt.Sprintf("in-%d", i); !filter.Contains(p) {
+			t.Fatalf("false negative for %q", p)
+		}
+	}
+
+	falsePositives := 0
+	const probes = 100000
+	for i := range probes {
+		if filter.Contains(fmt.Sprintf("out-%d", i)) {
+			falsePositives++
+		}
+	}
+	// Allow for variance around the 0.1% target
+	if rate := float64(falsePositives) / probes; rate > 3*falsePositiveRate {
+		t.Fatalf("false positive rate %.4f, want about %.4f", rate, falsePositiveRate)
+	}
+}
+
+func TestContainsWithoutChecker(t *testing.T) {
+	SetChecker(nil)
+	if Contains("123456") {
+		t.Fatal("Contains without a checker reported a breach")
+	}
+	SetChecker(NewBloomFilter(1, falsePositiveRate))
+	t.Cleanup(func() { SetChecker(nil) })
+	if Contains("123456") {
+		t.Fatal("empty filter reported a breach")
+	}
+}
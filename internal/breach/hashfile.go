@@ -0,0 +1,80 @@
+package breach
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+// hashFile binary-searches a sorted file of uppercase SHA-1 hashes without
+// loading it; the OS page cache keeps the hot pages in memory
+type hashFile struct {
+	f    *os.File
+	size int64
+}
+
+func newHashFile(f *os.File) (*hashFile, error) {
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &hashFile{f: f, size: info.Size()}, nil
+}
+
+// Contains looks up the SHA-1 of password
+func (h *hashFile) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	target := bytes.ToUpper([]byte(hex.EncodeToString(sum[:])))
+
+	// Lines starting before lo sort before target; lines starting at or
+	// after hi sort after it
+	lo, hi := int64(0), h.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, key, err := h.lineAt(mid)
+		if err != nil || start >= hi {
+			hi = mid
+			continue
+		}
+		switch c := bytes.Compare(key, target); {
+		case c == 0:
+			return true
+		case c < 0:
+			lo = start + 1
+		default:
+			hi = mid
+		}
+	}
+	return false
+}
+
+// lineAt finds the first line starting at or after off and returns its
+// offset and uppercased hash
+func (h *hashFile) lineAt(off int64) (int64, []byte, error) {
+	start := off
+	if off > 0 {
+		// Skip the rest of the line off-1 belongs to
+		buf := make([]byte, 128)
+		pos := off - 1
+		for {
+			n, err := h.f.ReadAt(buf, pos)
+			if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+				start = pos + int64(i) + 1
+				break
+			}
+			if err != nil {
+				return 0, nil, io.EOF
+			}
+			pos += int64(n)
+		}
+	}
+
+	key := make([]byte, 40)
+	if _, err := h.f.ReadAt(key, start); err != nil {
+		return 0, nil, err
+	}
+	return start, bytes.ToUpper(key), nil
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// Env returns an environment variable or a fallback when it is unset
+func Env(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// EnvInt returns a positive integer environment variable or a fallback
+func EnvInt(key string, fallback int) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
@@ -6,7 +6,7 @@ import (
 	"os"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"auth-service/internal/passwordhash"
 )
 
 // SeedInitialData ensures the super_admin role and Super Admin user exist
@@ -47,20 +47,25 @@ func SeedInitialData() {
 		log.Println("⚠️  Super Admin user not found, creating...")
 
 		// Hash password
-		hashed, err := bcrypt.GenerateFromPassword([]byte(superAdminPassword), bcrypt.DefaultCost)
+		hashed, err := passwordhash.Default().Hash(superAdminPassword)
 		if err != nil {
 			log.Fatalf("❌ Failed to hash Super Admin password: %v", err)
 		}
 
-		// Insert user
+		// Insert user (its email comes from the operator, so it counts as verified)
 		err = DB.QueryRow(ctx,
-			"INSERT INTO users (email, password_hash, is_active, created_at, updated_at) VALUES ($1, $2, TRUE, NOW(), NOW()) RETURNING id;",
-			superAdminEmail, string(hashed),
+			"INSERT INTO users (email, password_hash, is_active, email_verified_at, created_at, updated_at) VALUES ($1, $2, TRUE, NOW(), NOW(), NOW()) RETURNING id;",
+			superAdminEmail, hashed,
 		).Scan(&userID)
 		if err != nil {
 			log.Fatalf("❌ Failed to create Super Admin user: %v", err)
 		}
 
+		_, err = DB.Exec(ctx, "INSERT INTO password_history (user_id, password_hash) VALUES ($1, $2);", userID, hashed)
+		if err != nil {
+			log.Printf("⚠️  Failed to record Super Admin password history: %v", err)
+		}
+
 		log.Printf("✅ Created Super Admin user (id=%d)", userID)
 	} else {
 		log.Printf("✅ Found Super Admin user (id=%d)", userID)
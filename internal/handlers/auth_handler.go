@@ -2,10 +2,18 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"log"
+	"math"
+	"strconv"
 	"time"
 
 	"auth-service/internal/db"
+	"auth-service/internal/lockout"
+	"auth-service/internal/mfa"
+	"auth-service/internal/passwordhash"
+	"auth-service/internal/profile"
+	rolespkg "auth-service/internal/roles"
 	"auth-service/internal/utils"
 	jwtpkg "auth-service/pkg/jwt"
 
@@ -16,6 +24,8 @@ import (
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Audience is the client the token is for; defaults to this service
+	Audience string `json:"audience"`
 }
 
 // UserInfoResponse defines structure for /me output
@@ -36,17 +46,58 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	// Fetch user
 	ctx := context.Background()
+	audience, ok := resolveAudience(ctx, req.Audience)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unknown audience",
+		})
+	}
+
+	// Brute-force protection: the same answer whether or not the email exists
+	status, err := lockout.Check(ctx, req.Email, c.IP())
+	if err != nil {
+		log.Printf("❌ Failed to check login lockout: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	if status.Locked {
+		c.Set(fiber.HeaderRetryAfter, retryAfterSeconds(status.RetryAfter))
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error": "Account temporarily locked due to too many failed login attempts",
+		})
+	}
+	if status.Throttled {
+		c.Set(fiber.HeaderRetryAfter, retryAfterSeconds(status.RetryAfter))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "Too many failed login attempts, try again later",
+		})
+	}
+
+	// Fetch user
 	var id int
 	var email string
 	var passwordHash string
 	var isActive bool
 
-	err := db.DB.QueryRow(ctx, "SELECT id, email, password_hash, is_active FROM users WHERE email=$1;", req.Email).
+	err = db.DB.QueryRow(ctx, "SELECT id, email, password_hash, is_active FROM users WHERE email=$1;", req.Email).
 		Scan(&id, &email, &passwordHash, &isActive)
 
 	if err != nil {
+		recordLoginFailure(ctx, req.Email, c.IP())
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid email or password",
+		})
+	}
+
+	// Verify password before revealing anything about the account
+	match, err := utils.CheckPassword(req.Password, passwordHash)
+	if err != nil {
+		return hashingError(c, err, "Failed to verify password")
+	}
+	if !match {
+		recordLoginFailure(ctx, req.Email, c.IP())
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid email or password",
 		})
@@ -59,32 +110,123 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	// Verify password
-	if !utils.CheckPassword(req.Password, passwordHash) {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid email or password",
-		})
+	if err := lockout.RecordSuccess(ctx, req.Email); err != nil {
+		log.Printf("⚠️  Failed to reset login failures: %v", err)
+	}
+
+	// The plaintext is only at hand now, so upgrade outdated hashes here
+	if utils.NeedsRehash(passwordHash) {
+		rehashPassword(ctx, id, req.Password, passwordHash)
 	}
 
 	// Fetch user roles
-	rows, err := db.DB.Query(ctx, `
-		SELECT r.name FROM roles r
-		JOIN user_roles ur ON r.id = ur.role_id
-		WHERE ur.user_id = $1;
-	`, id)
+	// Forced MFA depends on the roles, so a failed lookup cannot fall through
+	roles, err := loadUserRoles(ctx, db.DB, id)
+	if err != nil {
+		log.Printf("❌ Failed to load roles for user %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+
+	// Second factor: enrolled users get a challenge, users whose role forces
+	// MFA but who have not enrolled must set it up first
+	methods, err := secondFactors(ctx, id)
+	if err != nil {
+		log.Printf("❌ Failed to check MFA for user %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	forced, err := mfa.Required(ctx, roles)
+	if err != nil {
+		log.Printf("❌ Failed to check MFA policy for user %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	if len(methods) > 0 || forced {
+		purpose, flag := mfa.PurposeVerify, "mfa_required"
+		if len(methods) == 0 {
+			purpose, flag = mfa.PurposeSetup, "mfa_setup_required"
+		}
+		mfaToken, ttl, err := mfa.NewChallenge(ctx, id, purpose, audience)
+		if err != nil {
+			log.Printf("❌ Failed to create MFA challenge: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to start MFA challenge",
+			})
+		}
+		return c.JSON(fiber.Map{
+			flag:         true,
+			"mfa_token":  mfaToken,
+			"methods":    methods,
+			"expires_in": int(ttl.Seconds()),
+		})
+	}
+
+	return startSession(ctx, c, id, email, roles, audience, nil)
+}
+
+// startSession opens a new session (refresh token family) and responds with
+// its tokens; extra fields are merged into the response
+func startSession(ctx context.Context, c *fiber.Ctx, id int, email string, roles []string, audience string, extra fiber.Map) error {
+	// Every login path (password, MFA, passkey) ends here, so this is where
+	// unverified email addresses and expired passwords are caught
+	unverified, err := emailUnverified(ctx, id)
+	if err != nil {
+		log.Printf("❌ Failed to check email verification for user %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	if unverified {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Email address not verified",
+		})
+	}
+
+	expired, err := passwordExpired(ctx, id)
+	if err != nil {
+		log.Printf("❌ Failed to check password age for user %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Database error",
+		})
+	}
+	if expired {
+		return passwordChangeRequired(c, id, email, extra)
+	}
+
+	sessionID, err := newSessionID()
 	if err != nil {
-		log.Printf("⚠️  Failed to load roles: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to start session",
+		})
+	}
+
+	refreshToken, _, err := issueRefreshToken(ctx, db.DB, id, sessionID, audience)
+	if err != nil {
+		log.Printf("❌ Failed to issue refresh token: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate refresh token",
+		})
 	}
 
-	var roles []string
-	for rows.Next() {
-		var roleName string
-		rows.Scan(&roleName)
-		roles = append(roles, roleName)
+	metadata, err := profile.Claims(ctx, id)
+	if err != nil {
+		log.Printf("❌ Failed to load metadata claims for user %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate access token",
+		})
 	}
 
 	// Generate JWT
-	token, err := jwtpkg.GenerateAccessToken(id, email, roles)
+	token, err := jwtpkg.GenerateAccessToken(id, email, roles, jwtpkg.TokenOptions{
+		SessionID: sessionID,
+		Audience:  audience,
+		TTL:       accessTokenTTL(ctx, audience),
+		Metadata:  metadata,
+	})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate access token",
@@ -92,15 +234,59 @@ func Login(c *fiber.Ctx) error {
 	}
 
 	// Return response
-	return c.JSON(fiber.Map{
-		"access_token": token,
+	resp := fiber.Map{
+		"access_token":  token,
+		"refresh_token": refreshToken,
 		"user": fiber.Map{
 			"id":     id,
 			"email":  email,
 			"roles":  roles,
 			"status": "active",
 		},
-	})
+	}
+	for k, v := range extra {
+		resp[k] = v
+	}
+	return c.JSON(resp)
+}
+
+// rehashPassword replaces an outdated hash; a failure only postpones the upgrade
+func rehashPassword(ctx context.Context, userID int, password, oldHash string) {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return
+	}
+	// Leave the row alone if the password changed in the meantime
+	_, err = db.DB.Exec(ctx, "UPDATE users SET password_hash = $1 WHERE id = $2 AND password_hash = $3;", hash, userID, oldHash)
+	if err != nil {
+		log.Printf("⚠️  Failed to rehash password for user %d: %v", userID, err)
+		return
+	}
+	log.Printf("🔐 Rehashed password for user %d", userID)
+}
+
+// recordLoginFailure counts a failed attempt; errors are logged, not surfaced
+func recordLoginFailure(ctx context.Context, email, ip string) {
+	if err := lockout.RecordFailure(ctx, email, ip); err != nil {
+		log.Printf("⚠️  Failed to record login failure: %v", err)
+	}
+}
+
+// hashingError answers a failed hash or password check: 503 with Retry-After
+// when the hashing pool is saturated, 500 otherwise
+func hashingError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, passwordhash.ErrOverloaded) {
+		c.Set(fiber.HeaderRetryAfter, retryAfterSeconds(passwordhash.DefaultPool().RetryAfter()))
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Server is busy, try again later",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": message})
+}
+
+// retryAfterSeconds formats a wait for the Retry-After header (whole seconds, at least 1)
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(max(1, int(math.Ceil(d.Seconds()))))
 }
 
 // GET /me
@@ -119,13 +305,25 @@ func Me(c *fiber.Ctx) error {
 		})
 	}
 
+	p, err := profile.Get(context.Background(), claims.UserID)
+	if err != nil {
+		return profileError(c, claims.UserID, err)
+	}
+
 	return c.JSON(fiber.Map{
 		"user": fiber.Map{
-			"id":    claims.UserID,
-			"email": claims.Email,
-			"roles": claims.Roles,
+			"id":      claims.UserID,
+			"email":   claims.Email,
+			"roles":   claims.Roles,
+			"profile": p,
 		},
 		"issued_at":  claims.IssuedAt.Time.Format(time.RFC3339),
 		"expires_at": claims.ExpiresAt.Time.Format(time.RFC3339),
 	})
 }
+
+// loadUserRoles returns the user's effective roles: those assigned directly
+// plus every role they inherit through the role hierarchy
+func loadUserRoles(ctx context.Context, q querier, userID int) ([]string, error) {
+	return rolespkg.EffectiveRoles(ctx, q, userID)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"auth-service/internal/authz"
+	"auth-service/internal/revocation"
+	rolespkg "auth-service/internal/roles"
+	jwtpkg "auth-service/pkg/jwt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxBatchChecks caps the size of a single /authorize/batch request
+const maxBatchChecks = 100
+
+// AuthorizeCheck is one "can the subject do action on resource" question
+type AuthorizeCheck struct {
+	Action   string `json:"action"`
+	Resource string `json:"resource"`
+}
+
+// AuthorizeRequest asks about the user behind Token, the user UserID, or the
+// caller itself when neither is given
+type AuthorizeRequest struct {
+	Token  string `json:"token"`
+	UserID int    `json:"user_id"`
+	AuthorizeCheck
+}
+
+// AuthorizeBatchRequest asks several questions about the same subject
+type AuthorizeBatchRequest struct {
+	Token  string           `json:"token"`
+	UserID int              `json:"user_id"`
+	Checks []AuthorizeCheck `json:"checks"`
+}
+
+// ✅ POST /authorize
+func Authorize(c *fiber.Ctx) error {
+	var req AuthorizeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON payload"})
+	}
+
+	ctx := context.Background()
+	userID, denyReason, status := resolveAuthorizeSubject(ctx, c, req.Token, req.UserID)
+	if status != fiber.StatusOK {
+		return c.Status(status).JSON(fiber.Map{"error": denyReason})
+	}
+	if denyReason != "" {
+		return c.JSON(denied(req.AuthorizeCheck, denyReason))
+	}
+
+	decision, err := authz.Decide(ctx, userID, req.Action, req.Resource)
+	if err != nil {
+		log.Printf("❌ Authorization check failed for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to evaluate authorization"})
+	}
+
+	return c.JSON(fiber.Map{
+		"user_id":    userID,
+		"action":     decision.Action,
+		"resource":   decision.Resource,
+		"permission": decision.Permission,
+		"allowed":    decision.Allowed,
+		"reason":     decision.Reason,
+	})
+}
+
+// ✅ POST /authorize/batch
+func AuthorizeBatch(c *fiber.Ctx) error {
+	var req AuthorizeBatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON payload"})
+	}
+	if len(req.Checks) == 0 || len(req.Checks) > maxBatchChecks {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "checks must contain between 1 and 100 entries"})
+	}
+
+	ctx := context.Background()
+	userID, denyReason, status := resolveAuthorizeSubject(ctx, c, req.Token, req.UserID)
+	if status != fiber.StatusOK {
+		return c.Status(status).JSON(fiber.Map{"error": denyReason})
+	}
+
+	results := make([]authz.Decision, 0, len(req.Checks))
+	for _, check := range req.Checks {
+		if denyReason != "" {
+			results = append(results, denied(check, denyReason))
+			continue
+		}
+
+		decision, err := authz.Decide(ctx, userID, check.Action, check.Resource)
+		if err != nil {
+			log.Printf("❌ Authorization check failed for user %d: %v", userID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to evaluate authorization"})
+		}
+		results = append(results, decision)
+	}
+
+	return c.JSON(fiber.Map{"user_id": userID, "results": results})
+}
+
+// resolveAuthorizeSubject works out whose permissions are being checked.
+// A non-empty denyReason means the subject's token is unusable and every
+// check must be denied; a status other than 200 aborts the request.
+func resolveAuthorizeSubject(ctx context.Context, c *fiber.Ctx, token string, userID int) (int, string, int) {
+	caller, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return 0, "Unauthorized", fiber.StatusUnauthorized
+	}
+
+	if token != "" {
+		claims, err := jwtpkg.ValidateServiceToken(token)
+		// Whether another user's token is valid, revoked or restricted is
+		// itself an answer, so the permission is checked before any of it
+		if err != nil || claims.UserID != caller.UserID {
+			if reason, status := requireAuthzCheck(ctx, caller.UserID); status != fiber.StatusOK {
+				return 0, reason, status
+			}
+		}
+		if errors.Is(err, jwtpkg.ErrRestrictedToken) {
+			return 0, "token is restricted to changing the password", fiber.StatusUnauthorized
+		}
+		if err != nil {
+			return 0, "token is invalid or expired", fiber.StatusOK
+		}
+		revoked, err := revocation.IsRevoked(ctx, claims)
+		if err != nil {
+			log.Printf("❌ Failed to check token revocation: %v", err)
+			return 0, "Failed to verify token", fiber.StatusInternalServerError
+		}
+		if revoked {
+			return 0, "token has been revoked", fiber.StatusOK
+		}
+		return claims.UserID, "", fiber.StatusOK
+	}
+
+	if userID != 0 && userID != caller.UserID {
+		if reason, status := requireAuthzCheck(ctx, caller.UserID); status != fiber.StatusOK {
+			return 0, reason, status
+		}
+		return userID, "", fiber.StatusOK
+	}
+	return caller.UserID, "", fiber.StatusOK
+}
+
+// requireAuthzCheck refuses callers without authz:check; asking about anyone
+// but yourself is reserved for trusted services
+func requireAuthzCheck(ctx context.Context, callerID int) (string, int) {
+	perms, err := rolespkg.UserPermissions(ctx, callerID)
+	if err != nil {
+		log.Printf("❌ Failed to load permissions for user %d: %v", callerID, err)
+		return "Failed to verify permissions", fiber.StatusInternalServerError
+	}
+	if !rolespkg.HasPermission(perms, "authz:check") {
+		return "Missing permission: authz:check", fiber.StatusForbidden
+	}
+	return "", fiber.StatusOK
+}
+
+func denied(check AuthorizeCheck, reason string) authz.Decision {
+	return authz.Decision{
+		Action:     check.Action,
+		Resource:   check.Resource,
+		Permission: authz.PermissionFor(check.Action, check.Resource),
+		Reason:     reason,
+	}
+}
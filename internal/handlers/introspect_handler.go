@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"auth-service/internal/db"
+	"auth-service/internal/revocation"
+	"auth-service/internal/utils"
+	jwtpkg "auth-service/pkg/jwt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IntrospectRequest follows RFC 7662 (form-encoded or JSON)
+type IntrospectRequest struct {
+	Token         string `json:"token" form:"token"`
+	TokenTypeHint string `json:"token_type_hint" form:"token_type_hint"`
+}
+
+// inactive is the only response RFC 7662 allows for unknown or invalid tokens
+var inactive = fiber.Map{"active": false}
+
+// ✅ POST /introspect
+// Lets services that cannot verify JWTs locally ask whether a token is live.
+// Roles and account status come from the database, not the token.
+func Introspect(c *fiber.Ctx) error {
+	if c.Locals("user") == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req IntrospectRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request"})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	ctx := context.Background()
+
+	if req.TokenTypeHint == "refresh_token" {
+		return c.JSON(introspectRefreshToken(ctx, req.Token))
+	}
+
+	// Tokens minted for any service audience can be introspected; a
+	// password-change token is not a usable access token
+	claims, err := jwtpkg.ValidateServiceToken(req.Token)
+	if errors.Is(err, jwtpkg.ErrRestrictedToken) {
+		return c.JSON(inactive)
+	}
+	if err != nil {
+		// Not a valid access token; it may still be a refresh token
+		return c.JSON(introspectRefreshToken(ctx, req.Token))
+	}
+
+	revoked, err := revocation.IsRevoked(ctx, claims)
+	if err != nil {
+		log.Printf("❌ Failed to check token revocation: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "server_error"})
+	}
+	if revoked {
+		return c.JSON(inactive)
+	}
+
+	resp, ok := introspectUser(ctx, claims.UserID)
+	if !ok {
+		return c.JSON(inactive)
+	}
+
+	resp["token_type"] = "Bearer"
+	resp["jti"] = claims.ID
+	resp["iss"] = claims.Issuer
+	resp["aud"] = claims.Audience
+	if claims.SessionID != "" {
+		resp["sid"] = claims.SessionID
+	}
+	if claims.ExpiresAt != nil {
+		resp["exp"] = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp["iat"] = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		resp["nbf"] = claims.NotBefore.Unix()
+	}
+	return c.JSON(resp)
+}
+
+// introspectRefreshToken reports on an opaque refresh token
+func introspectRefreshToken(ctx context.Context, token string) fiber.Map {
+	var userID int
+	var expiresAt, createdAt time.Time
+	var revoked bool
+	err := db.DB.QueryRow(ctx, `
+		SELECT user_id, expires_at, created_at, revoked
+		FROM refresh_tokens
+		WHERE token = $1;
+	`, utils.HashToken(token)).Scan(&userID, &expiresAt, &createdAt, &revoked)
+	if err != nil || revoked || time.Now().UTC().After(expiresAt) {
+		return inactive
+	}
+
+	resp, ok := introspectUser(ctx, userID)
+	if !ok {
+		return inactive
+	}
+	resp["token_type"] = "refresh_token"
+	resp["exp"] = expiresAt.Unix()
+	resp["iat"] = createdAt.Unix()
+	return resp
+}
+
+// introspectUser builds the active response for a user, or reports false if
+// the user no longer exists or has been deactivated
+func introspectUser(ctx context.Context, userID int) (fiber.Map, bool) {
+	var email string
+	var isActive bool
+	err := db.DB.QueryRow(ctx, "SELECT email, is_active FROM users WHERE id=$1;", userID).Scan(&email, &isActive)
+	if err != nil || !isActive {
+		return nil, false
+	}
+
+	roles, err := loadUserRoles(ctx, db.DB, userID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load roles: %v", err)
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+
+	return fiber.Map{
+		"active":   true,
+		"sub":      strconv.Itoa(userID),
+		"username": email,
+		"email":    email,
+		"roles":    roles,
+	}, true
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"log"
+
+	jwtpkg "auth-service/pkg/jwt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ✅ GET /.well-known/jwks.json
+// Publishes the public verification keys so other services can verify tokens
+// without being able to mint them
+func GetJWKS(c *fiber.Ctx) error {
+	set, err := jwtpkg.PublicJWKS()
+	if err != nil {
+		log.Printf("❌ Failed to build JWKS: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load signing keys"})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(set)
+}
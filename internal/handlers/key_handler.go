@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"auth-service/internal/keystore"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ✅ GET /superadmin/keys
+func ListSigningKeys(c *fiber.Ctx) error {
+	keys, err := keystore.List(context.Background())
+	if err != nil {
+		log.Printf("❌ Failed to list signing keys: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch signing keys"})
+	}
+
+	return c.JSON(fiber.Map{"keys": keys})
+}
+
+// ✅ POST /superadmin/keys
+// Generates a new key; without activates_at it is only published (pending)
+// until promoted, giving consumers time to fetch it from the JWKS
+func CreateSigningKey(c *fiber.Ctx) error {
+	var body struct {
+		Algorithm   string     `json:"alg"`
+		ActivatesAt *time.Time `json:"activates_at"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON payload"})
+	}
+	if body.Algorithm == "" {
+		body.Algorithm = "ES256"
+	}
+
+	// Far-future activation means "pending until promoted"
+	activatesAt := time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+	if body.ActivatesAt != nil {
+		activatesAt = *body.ActivatesAt
+	}
+
+	ctx := context.Background()
+	key, err := keystore.Generate(ctx, body.Algorithm, activatesAt)
+	if errors.Is(err, keystore.ErrUnsupportedAlgorithm) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unsupported signing algorithm (use RS256, ES256, ES384 or EdDSA)"})
+	}
+	if err != nil {
+		log.Printf("❌ Failed to generate signing key: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate signing key"})
+	}
+	if err := keystore.Reload(ctx); err != nil {
+		log.Printf("⚠️  Failed to reload signing keys: %v", err)
+	}
+
+	log.Printf("🔑 Generated %s signing key %s", key.Algorithm, key.KID)
+	return c.JSON(fiber.Map{"message": "Signing key created successfully", "key": key})
+}
+
+// ✅ POST /superadmin/keys/:kid/promote
+func PromoteSigningKey(c *fiber.Ctx) error {
+	kid := c.Params("kid")
+	ctx := context.Background()
+	if err := keystore.Promote(ctx, kid); err != nil {
+		if errors.Is(err, keystore.ErrKeyNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Signing key not found"})
+		}
+		log.Printf("❌ Failed to promote signing key %s: %v", kid, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to promote signing key"})
+	}
+	if err := keystore.Reload(ctx); err != nil {
+		log.Printf("⚠️  Failed to reload signing keys: %v", err)
+	}
+
+	log.Printf("🔑 Promoted signing key %s", kid)
+	return c.JSON(fiber.Map{"message": "Signing key promoted successfully"})
+}
+
+// ✅ POST /superadmin/keys/:kid/retire
+// Without retires_at the key is retired immediately
+func RetireSigningKey(c *fiber.Ctx) error {
+	var body struct {
+		RetiresAt *time.Time `json:"retires_at"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON payload"})
+		}
+	}
+	retiresAt := time.Now()
+	if body.RetiresAt != nil {
+		retiresAt = *body.RetiresAt
+	}
+
+	kid := c.Params("kid")
+	ctx := context.Background()
+	if err := keystore.Retire(ctx, kid, retiresAt); err != nil {
+		if errors.Is(err, keystore.ErrKeyNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Signing key not found"})
+		}
+		log.Printf("❌ Failed to retire signing key %s: %v", kid, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retire signing key"})
+	}
+	if err := keystore.Reload(ctx); err != nil {
+		log.Printf("⚠️  Failed to reload signing keys: %v", err)
+	}
+
+	log.Printf("🔑 Retiring signing key %s at %s", kid, retiresAt.Format(time.RFC3339))
+	return c.JSON(fiber.Map{"message": "Signing key retirement scheduled"})
+}
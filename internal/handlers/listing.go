@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"auth-service/internal/db"
+	"auth-service/internal/listing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// listPage serves one page of a listing endpoint. Query parameters: limit,
+// sort (field or -field), cursor (next_cursor of the previous page) and the
+// spec's filters.
+func listPage[T any](c *fiber.Ctx, spec listing.Spec, field string, scan func(scan func(dest ...any) error) (T, error)) error {
+	q, err := listing.Parse(spec, c.Queries())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	page, err := listing.Fetch(context.Background(), db.DB, q, scan)
+	if err != nil {
+		log.Printf("❌ Error listing %s: %v", field, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+
+	var next any
+	if page.NextCursor != "" {
+		next = page.NextCursor
+	}
+	return c.JSON(fiber.Map{
+		field:         page.Items,
+		"total":       page.Total,
+		"limit":       page.Limit,
+		"next_cursor": next,
+	})
+}
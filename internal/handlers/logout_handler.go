@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"auth-service/internal/revocation"
+	jwtpkg "auth-service/pkg/jwt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ✅ POST /logout
+// Revokes the presented access token and the refresh tokens of its session
+func Logout(c *fiber.Ctx) error {
+	user := c.Locals("user")
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	claims := user.(*jwtpkg.CustomClaims)
+
+	ctx := context.Background()
+	if err := revocation.RevokeToken(ctx, claims); err != nil {
+		log.Printf("❌ Failed to revoke token for user %d: %v", claims.UserID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to logout"})
+	}
+	if err := revocation.RevokeSession(ctx, claims.SessionID); err != nil {
+		log.Printf("❌ Failed to revoke session for user %d: %v", claims.UserID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to logout"})
+	}
+
+	log.Printf("👋 User %d logged out", claims.UserID)
+	return c.JSON(fiber.Map{"message": "Logged out successfully"})
+}
+
+// ✅ POST /logout-all
+// Revokes every access and refresh token the user holds, on all devices
+func LogoutAll(c *fiber.Ctx) error {
+	user := c.Locals("user")
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	claims := user.(*jwtpkg.CustomClaims)
+
+	if err := revocation.RevokeAllForUser(context.Background(), claims.UserID); err != nil {
+		log.Printf("❌ Failed to revoke tokens for user %d: %v", claims.UserID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to logout"})
+	}
+
+	log.Printf("👋 User %d logged out of all sessions", claims.UserID)
+	return c.JSON(fiber.Map{"message": "Logged out of all sessions"})
+}
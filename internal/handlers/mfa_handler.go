@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+
+	"auth-service/internal/audit"
+	"auth-service/internal/db"
+	"auth-service/internal/mfa"
+	"auth-service/internal/passkeys"
+	jwtpkg "auth-service/pkg/jwt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// MFARequest carries a challenge token from /login and/or a code
+type MFARequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// POST /login/mfa
+// Completes a login with a TOTP code or a recovery code
+func LoginMFA(c *fiber.Ctx) error {
+	var req MFARequest
+	if err := c.BodyParser(&req); err != nil || req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	ctx := context.Background()
+	ch, err := mfa.LoadChallenge(ctx, req.MFAToken, mfa.PurposeVerify)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+
+	email, ok, err := loadActiveUser(ctx, ch.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User account is inactive"})
+	}
+
+	valid, err := verifySecondFactor(ctx, ch.UserID, req)
+	if err != nil {
+		log.Printf("❌ Failed to verify MFA code for user %d: %v", ch.UserID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify MFA code"})
+	}
+	if !valid {
+		if err := ch.Fail(ctx); err != nil {
+			log.Printf("⚠️  Failed to record MFA failure: %v", err)
+		}
+		recordLoginFailure(ctx, email, c.IP())
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid MFA code"})
+	}
+
+	if err := ch.Consume(ctx); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+
+	roles, err := loadUserRoles(ctx, db.DB, ch.UserID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load roles: %v", err)
+	}
+	if req.RecoveryCode != "" {
+		log.Printf("🔑 User %d logged in with a recovery code", ch.UserID)
+	}
+	return startSession(ctx, c, ch.UserID, email, roles, ch.Audience, nil)
+}
+
+// POST /login/mfa/enroll
+// Starts TOTP enrollment for a user whose role requires MFA
+func LoginMFAEnroll(c *fiber.Ctx) error {
+	var req MFARequest
+	if err := c.BodyParser(&req); err != nil || req.MFAToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	ctx := context.Background()
+	ch, err := mfa.LoadChallenge(ctx, req.MFAToken, mfa.PurposeSetup)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+
+	return startEnrollment(ctx, c, ch.UserID)
+}
+
+// POST /login/mfa/enroll/confirm
+// Confirms enrollment with a first code and completes the login
+func LoginMFAEnrollConfirm(c *fiber.Ctx) error {
+	var req MFARequest
+	if err := c.BodyParser(&req); err != nil || req.MFAToken == "" || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	ctx := context.Background()
+	ch, err := mfa.LoadChallenge(ctx, req.MFAToken, mfa.PurposeSetup)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+
+	email, ok, err := loadActiveUser(ctx, ch.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User account is inactive"})
+	}
+
+	codes, err := mfa.ConfirmEnrollment(ctx, ch.UserID, req.Code)
+	if err != nil {
+		if errors.Is(err, mfa.ErrInvalidCode) {
+			if err := ch.Fail(ctx); err != nil {
+				log.Printf("⚠️  Failed to record MFA failure: %v", err)
+			}
+		}
+		return enrollmentError(c, ch.UserID, err)
+	}
+
+	if err := ch.Consume(ctx); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+
+	roles, err := loadUserRoles(ctx, db.DB, ch.UserID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load roles: %v", err)
+	}
+
+	log.Printf("🔐 Enabled MFA for user %d", ch.UserID)
+	return startSession(ctx, c, ch.UserID, email, roles, ch.Audience, fiber.Map{"recovery_codes": codes})
+}
+
+// GET /mfa
+func GetMFAStatus(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	status, err := mfa.GetStatus(context.Background(), claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load MFA status"})
+	}
+	return c.JSON(status)
+}
+
+// POST /mfa/totp/enroll
+func EnrollTOTP(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	return startEnrollment(context.Background(), c, claims.UserID)
+}
+
+// POST /mfa/totp/confirm
+func ConfirmTOTP(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req MFARequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	codes, err := mfa.ConfirmEnrollment(context.Background(), claims.UserID, req.Code)
+	if err != nil {
+		return enrollmentError(c, claims.UserID, err)
+	}
+
+	log.Printf("🔐 Enabled MFA for user %d", claims.UserID)
+	return c.JSON(fiber.Map{
+		"message":        "MFA enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DELETE /mfa/totp
+// Requires a current code; refused while one of the user's roles forces MFA
+func DisableTOTP(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req MFARequest
+	if err := c.BodyParser(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	ctx := context.Background()
+	roles, err := loadUserRoles(ctx, db.DB, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load roles"})
+	}
+	forced, err := mfa.Required(ctx, roles)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load the MFA policy"})
+	}
+	if forced {
+		// A passkey still satisfies the requirement without TOTP
+		has, err := passkeys.HasCredentials(ctx, claims.UserID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load passkeys"})
+		}
+		if !has {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "MFA is required for your role"})
+		}
+	}
+
+	if ok, err := verifySecondFactor(ctx, claims.UserID, req); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify MFA code"})
+	} else if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid MFA code"})
+	}
+
+	if err := mfa.Disable(ctx, claims.UserID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to disable MFA"})
+	}
+
+	log.Printf("🔓 Disabled MFA for user %d", claims.UserID)
+	return c.JSON(fiber.Map{"message": "MFA disabled"})
+}
+
+// POST /mfa/recovery-codes
+// Replaces all recovery codes; requires a current code
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req MFARequest
+	if err := c.BodyParser(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	ctx := context.Background()
+	if ok, err := verifySecondFactor(ctx, claims.UserID, req); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify MFA code"})
+	} else if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid MFA code"})
+	}
+
+	codes, err := mfa.RegenerateRecoveryCodes(ctx, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate recovery codes"})
+	}
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// ✅ DELETE /admin/users/:id/mfa
+// Removes a user's MFA (lost device); a forced role makes them re-enroll at next login
+func ResetUserMFA(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	ctx := context.Background()
+	var exists bool
+	if err := db.DB.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1);", userID).Scan(&exists); err != nil {
+		log.Printf("❌ Failed to load user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset MFA"})
+	}
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	// Resetting MFA weakens the account, so an admin may only do it to
+	// accounts whose roles they hold themselves
+	if ok, err := requireRank(ctx, c, userID, claims.UserID, "reset MFA of"); !ok {
+		return err
+	}
+
+	if err := mfa.Disable(ctx, userID); err != nil {
+		log.Printf("❌ Failed to reset MFA for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset MFA"})
+	}
+	if err := audit.Record(ctx, userID, "mfa_reset", map[string]any{"by": claims.UserID}); err != nil {
+		log.Printf("⚠️  Failed to write audit log for user %d: %v", userID, err)
+	}
+
+	log.Printf("🔓 Reset MFA for user %d", userID)
+	return c.JSON(fiber.Map{"message": "MFA reset successfully"})
+}
+
+func startEnrollment(ctx context.Context, c *fiber.Ctx, userID int) error {
+	var email string
+	if err := db.DB.QueryRow(ctx, "SELECT email FROM users WHERE id=$1;", userID).Scan(&email); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	secret, uri, err := mfa.StartEnrollment(ctx, userID, email)
+	if err != nil {
+		return enrollmentError(c, userID, err)
+	}
+	return c.JSON(fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": uri,
+	})
+}
+
+func enrollmentError(c *fiber.Ctx, userID int, err error) error {
+	switch {
+	case errors.Is(err, mfa.ErrAlreadyEnabled):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "MFA is already enabled"})
+	case errors.Is(err, mfa.ErrNotEnrolled):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Start enrollment first"})
+	case errors.Is(err, mfa.ErrInvalidCode):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid MFA code"})
+	default:
+		log.Printf("❌ MFA enrollment failed for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "MFA enrollment failed"})
+	}
+}
+
+// secondFactors lists the MFA methods a user can answer a login challenge with
+func secondFactors(ctx context.Context, userID int) ([]string, error) {
+	methods := []string{}
+
+	totp, err := mfa.Enabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp {
+		methods = append(methods, "totp", "recovery_code")
+	}
+
+	webauthn, err := passkeys.HasCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if webauthn {
+		methods = append(methods, "webauthn")
+	}
+	return methods, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or a recovery code
+func verifySecondFactor(ctx context.Context, userID int, req MFARequest) (bool, error) {
+	if req.Code != "" {
+		return mfa.VerifyCode(ctx, userID, req.Code)
+	}
+	return mfa.UseRecoveryCode(ctx, userID, req.RecoveryCode)
+}
+
+// loadActiveUser returns the user's email and whether the account may log in
+func loadActiveUser(ctx context.Context, userID int) (string, bool, error) {
+	var email string
+	var isActive bool
+	err := db.DB.QueryRow(ctx, "SELECT email, is_active FROM users WHERE id=$1;", userID).Scan(&email, &isActive)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	return email, isActive, nil
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+
+	"auth-service/internal/db"
+	"auth-service/internal/mfa"
+	"auth-service/internal/passkeys"
+	jwtpkg "auth-service/pkg/jwt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PasskeyRequest carries a ceremony's session token and the browser's
+// PublicKeyCredential (as produced by navigator.credentials.create/get)
+type PasskeyRequest struct {
+	SessionToken string          `json:"session_token"`
+	Credential   json.RawMessage `json:"credential"`
+	Name         string          `json:"name"`
+	MFAToken     string          `json:"mfa_token"`
+	Audience     string          `json:"audience"`
+}
+
+// POST /webauthn/register/begin
+func BeginPasskeyRegistration(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	options, sessionToken, err := passkeys.BeginRegistration(context.Background(), claims.UserID)
+	if err != nil {
+		log.Printf("❌ Failed to start passkey registration for user %d: %v", claims.UserID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start passkey registration"})
+	}
+	return c.JSON(fiber.Map{
+		"session_token": sessionToken,
+		"options":       options,
+	})
+}
+
+// POST /webauthn/register/finish
+func FinishPasskeyRegistration(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req PasskeyRequest
+	if err := c.BodyParser(&req); err != nil || req.SessionToken == "" || len(req.Credential) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	info, err := passkeys.FinishRegistration(context.Background(), claims.UserID, req.SessionToken, req.Name, req.Credential)
+	if err != nil {
+		return passkeyError(c, claims.UserID, err)
+	}
+
+	log.Printf("🔐 Registered passkey %d for user %d", info.ID, claims.UserID)
+	return c.Status(fiber.StatusCreated).JSON(info)
+}
+
+// GET /webauthn/credentials
+func ListPasskeys(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	creds, err := passkeys.List(context.Background(), claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load passkeys"})
+	}
+	return c.JSON(creds)
+}
+
+// POST /webauthn/reauth/begin
+// Starts a passkey assertion that proves a second factor for a sensitive
+// change (deleting a passkey)
+func BeginPasskeyReauth(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	options, sessionToken, err := passkeys.BeginLogin(context.Background(), claims.UserID)
+	if err != nil {
+		return passkeyError(c, claims.UserID, err)
+	}
+	return c.JSON(fiber.Map{
+		"session_token": sessionToken,
+		"options":       options,
+	})
+}
+
+// DeletePasskeyRequest proves a second factor: a TOTP or recovery code, or a
+// passkey assertion started with /webauthn/reauth/begin
+type DeletePasskeyRequest struct {
+	Code         string          `json:"code"`
+	RecoveryCode string          `json:"recovery_code"`
+	SessionToken string          `json:"session_token"`
+	Credential   json.RawMessage `json:"credential"`
+}
+
+// DELETE /webauthn/credentials/:id
+// Requires a fresh second factor; refused for the last factor while one of
+// the user's roles forces MFA
+func DeletePasskey(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid passkey ID"})
+	}
+
+	var req DeletePasskeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+	assertion := req.SessionToken != "" && len(req.Credential) > 0
+	if !assertion && req.Code == "" && req.RecoveryCode == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A second factor is required to delete a passkey"})
+	}
+
+	ctx := context.Background()
+	if last, err := lastForcedFactor(ctx, claims.UserID); err != nil {
+		log.Printf("❌ Failed to check MFA requirements for user %d: %v", claims.UserID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load MFA settings"})
+	} else if last {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "MFA is required for your role"})
+	}
+
+	if assertion {
+		if err := passkeys.FinishLogin(ctx, claims.UserID, req.SessionToken, req.Credential); err != nil {
+			return passkeyError(c, claims.UserID, err)
+		}
+	} else if ok, err := verifySecondFactor(ctx, claims.UserID, MFARequest{Code: req.Code, RecoveryCode: req.RecoveryCode}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify MFA code"})
+	} else if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid MFA code"})
+	}
+
+	if err := passkeys.Delete(ctx, claims.UserID, id); err != nil {
+		if errors.Is(err, passkeys.ErrCredentialNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Passkey not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete passkey"})
+	}
+
+	log.Printf("🗑️  Deleted passkey %d of user %d", id, claims.UserID)
+	return c.JSON(fiber.Map{"message": "Passkey deleted successfully"})
+}
+
+// lastForcedFactor reports whether deleting one passkey would leave a user
+// whose role forces MFA without any second factor
+func lastForcedFactor(ctx context.Context, userID int) (bool, error) {
+	roles, err := loadUserRoles(ctx, db.DB, userID)
+	if err != nil {
+		return false, err
+	}
+	forced, err := mfa.Required(ctx, roles)
+	if err != nil || !forced {
+		return false, err
+	}
+	totp, err := mfa.Enabled(ctx, userID)
+	if err != nil || totp {
+		return false, err
+	}
+	creds, err := passkeys.List(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return len(creds) <= 1, nil
+}
+
+// POST /login/passkey/begin
+// Starts a passwordless login; the authenticator picks the account
+func BeginPasskeyLogin(c *fiber.Ctx) error {
+	options, sessionToken, err := passkeys.BeginPasswordless(context.Background())
+	if err != nil {
+		log.Printf("❌ Failed to start passkey login: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start passkey login"})
+	}
+	return c.JSON(fiber.Map{
+		"session_token": sessionToken,
+		"options":       options,
+	})
+}
+
+// POST /login/passkey/finish
+// A user-verified passkey is already multi-factor, so no MFA challenge follows
+func FinishPasskeyLogin(c *fiber.Ctx) error {
+	var req PasskeyRequest
+	if err := c.BodyParser(&req); err != nil || req.SessionToken == "" || len(req.Credential) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	ctx := context.Background()
+	audience, ok := resolveAudience(ctx, req.Audience)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown audience"})
+	}
+
+	userID, err := passkeys.FinishPasswordless(ctx, req.SessionToken, req.Credential)
+	if err != nil {
+		return passkeyError(c, 0, err)
+	}
+
+	email, active, err := loadActiveUser(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	if !active {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User account is inactive"})
+	}
+
+	roles, err := loadUserRoles(ctx, db.DB, userID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load roles: %v", err)
+	}
+	return startSession(ctx, c, userID, email, roles, audience, nil)
+}
+
+// POST /login/mfa/webauthn/begin
+// Starts a passkey assertion as the second factor of a password login
+func BeginMFAPasskey(c *fiber.Ctx) error {
+	var req PasskeyRequest
+	if err := c.BodyParser(&req); err != nil || req.MFAToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	ctx := context.Background()
+	ch, err := mfa.LoadChallenge(ctx, req.MFAToken, mfa.PurposeVerify)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+
+	options, sessionToken, err := passkeys.BeginLogin(ctx, ch.UserID)
+	if err != nil {
+		return passkeyError(c, ch.UserID, err)
+	}
+	return c.JSON(fiber.Map{
+		"session_token": sessionToken,
+		"options":       options,
+	})
+}
+
+// POST /login/mfa/webauthn/finish
+func FinishMFAPasskey(c *fiber.Ctx) error {
+	var req PasskeyRequest
+	if err := c.BodyParser(&req); err != nil || req.MFAToken == "" || req.SessionToken == "" || len(req.Credential) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	ctx := context.Background()
+	ch, err := mfa.LoadChallenge(ctx, req.MFAToken, mfa.PurposeVerify)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+
+	email, active, err := loadActiveUser(ctx, ch.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	if !active {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User account is inactive"})
+	}
+
+	if err := passkeys.FinishLogin(ctx, ch.UserID, req.SessionToken, req.Credential); err != nil {
+		if err := ch.Fail(ctx); err != nil {
+			log.Printf("⚠️  Failed to record MFA failure: %v", err)
+		}
+		recordLoginFailure(ctx, email, c.IP())
+		return passkeyError(c, ch.UserID, err)
+	}
+
+	if err := ch.Consume(ctx); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+
+	roles, err := loadUserRoles(ctx, db.DB, ch.UserID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load roles: %v", err)
+	}
+	return startSession(ctx, c, ch.UserID, email, roles, ch.Audience, nil)
+}
+
+func passkeyError(c *fiber.Ctx, userID int, err error) error {
+	switch {
+	case errors.Is(err, passkeys.ErrSessionInvalid):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired WebAuthn session"})
+	case errors.Is(err, passkeys.ErrNoCredentials):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No passkeys registered"})
+	case errors.Is(err, passkeys.ErrCloneDetected):
+		log.Printf("🚨 Passkey sign count regression for user %d (possible cloned authenticator)", userID)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Passkey verification failed"})
+	default:
+		log.Printf("⚠️  Passkey verification failed for user %d: %v", userID, err)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Passkey verification failed"})
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"auth-service/internal/audit"
+	"auth-service/internal/db"
+	"auth-service/internal/passwordhistory"
+	"auth-service/internal/passwordpolicy"
+	"auth-service/internal/revocation"
+	"auth-service/internal/utils"
+	jwtpkg "auth-service/pkg/jwt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// ChangePasswordRequest defines incoming payload for /me/password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// POST /me/password
+// Changes the caller's password; every other session is signed out
+func ChangePassword(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.CurrentPassword == "" || req.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	ctx := context.Background()
+	var email, passwordHash string
+	err := db.DB.QueryRow(ctx, "SELECT email, password_hash FROM users WHERE id = $1 AND is_active = TRUE;", claims.UserID).
+		Scan(&email, &passwordHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if err != nil {
+		log.Printf("❌ Failed to load user %d: %v", claims.UserID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+
+	match, err := utils.CheckPassword(req.CurrentPassword, passwordHash)
+	if err != nil {
+		return hashingError(c, err, "Failed to verify password")
+	}
+	if !match {
+		log.Printf("🚫 Wrong current password on password change for user %d", claims.UserID)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Current password is incorrect"})
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "New password must differ from the current one"})
+	}
+	if violations, err := passwordpolicy.Check(ctx, req.NewPassword, email); err != nil {
+		return policyUnavailable(c, err)
+	} else if len(violations) > 0 {
+		return weakPassword(c, violations)
+	}
+	if violations, err := passwordReuse(ctx, claims.UserID, req.NewPassword); err != nil {
+		return hashingError(c, err, "Failed to check password history")
+	} else if len(violations) > 0 {
+		return weakPassword(c, violations)
+	}
+
+	hash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return hashingError(c, err, "Failed to hash password")
+	}
+
+	_, err = db.DB.Exec(ctx, `
+		UPDATE users SET password_hash = $1, password_changed_at = NOW(), updated_at = NOW()
+		WHERE id = $2;
+	`, hash, claims.UserID)
+	if err != nil {
+		log.Printf("❌ Failed to change password for user %d: %v", claims.UserID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to change password"})
+	}
+	recordPasswordHistory(ctx, claims.UserID, hash)
+
+	// Keep the session that made the change, end all the others. A
+	// password-change token has no session, so every session ends.
+	if err := revocation.RevokeOtherSessions(ctx, claims.UserID, claims.SessionID); err != nil {
+		log.Printf("❌ Failed to revoke other sessions for user %d: %v", claims.UserID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke other sessions"})
+	}
+	restricted := slices.Contains(claims.Audience, jwtpkg.PasswordChangeAudience)
+	if restricted {
+		if err := revocation.RevokeToken(ctx, claims); err != nil {
+			log.Printf("⚠️  Failed to revoke password change token for user %d: %v", claims.UserID, err)
+		}
+	}
+
+	if err := audit.Record(ctx, claims.UserID, "password_changed", map[string]any{
+		"ip":  c.IP(),
+		"sid": claims.SessionID,
+	}); err != nil {
+		log.Printf("⚠️  Failed to write audit log for user %d: %v", claims.UserID, err)
+	}
+
+	log.Printf("🔑 User %d changed their password", claims.UserID)
+	if restricted {
+		return c.JSON(fiber.Map{"message": "Password changed successfully, log in with the new password"})
+	}
+	return c.JSON(fiber.Map{"message": "Password changed successfully"})
+}
+
+// passwordChangeTokenTTL bounds the restricted token issued for an expired password
+const passwordChangeTokenTTL = 10 * time.Minute
+
+// passwordExpired reports whether the user's password is older than password_max_age_days
+func passwordExpired(ctx context.Context, userID int) (bool, error) {
+	if passwordhistory.MaxAge(ctx) <= 0 {
+		return false, nil
+	}
+	var changedAt time.Time
+	err := db.DB.QueryRow(ctx, "SELECT password_changed_at FROM users WHERE id = $1;", userID).Scan(&changedAt)
+	if err != nil {
+		return false, err
+	}
+	return passwordhistory.Expired(ctx, changedAt), nil
+}
+
+// passwordChangeRequired answers a login with an expired password: instead of
+// a session the user gets a short-lived token accepted only by POST /me/password.
+// extra fields (such as fresh recovery codes) are still passed on.
+func passwordChangeRequired(c *fiber.Ctx, id int, email string, extra fiber.Map) error {
+	token, err := jwtpkg.GenerateAccessToken(id, email, nil, jwtpkg.TokenOptions{
+		Audience: jwtpkg.PasswordChangeAudience,
+		TTL:      passwordChangeTokenTTL,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate access token",
+		})
+	}
+
+	log.Printf("⏳ Password of user %d has expired, password change required", id)
+	resp := fiber.Map{
+		"password_change_required": true,
+		"password_change_token":    token,
+		"expires_in":               int(passwordChangeTokenTTL.Seconds()),
+	}
+	for k, v := range extra {
+		resp[k] = v
+	}
+	return c.JSON(resp)
+}
+
+// passwordReuse reports a violation when password is one of the user's recent passwords
+func passwordReuse(ctx context.Context, userID int, password string) ([]passwordpolicy.Violation, error) {
+	reused, err := passwordhistory.Reused(ctx, userID, password)
+	if err != nil || !reused {
+		return nil, err
+	}
+	return []passwordpolicy.Violation{{
+		Rule:    "history",
+		Message: fmt.Sprintf("Password must differ from your last %d passwords", passwordhistory.Size(ctx)),
+	}}, nil
+}
+
+// recordPasswordHistory remembers a newly set hash; failures are logged, not surfaced
+func recordPasswordHistory(ctx context.Context, userID int, hash string) {
+	if err := passwordhistory.Record(ctx, userID, hash); err != nil {
+		log.Printf("⚠️  Failed to record password history for user %d: %v", userID, err)
+	}
+}
+
+// policyUnavailable refuses a password change when the password rules cannot
+// be read, rather than accepting it under the defaults
+func policyUnavailable(c *fiber.Ctx, err error) error {
+	log.Printf("❌ Failed to load the password policy: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load the password policy"})
+}
+
+// weakPassword answers a password that breaks the password policy, listing every failed rule
+func weakPassword(c *fiber.Ctx, violations []passwordpolicy.Violation) error {
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+		"error":      "Password does not meet the password policy",
+		"violations": violations,
+	})
+}
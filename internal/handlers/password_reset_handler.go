@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/db"
+	"auth-service/internal/lockout"
+	"auth-service/internal/mailer"
+	"auth-service/internal/passwordpolicy"
+	"auth-service/internal/policies"
+	"auth-service/internal/revocation"
+	"auth-service/internal/usertokens"
+	"auth-service/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// ForgotPasswordRequest defines incoming payload for /forgot-password
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest defines incoming payload for /reset-password
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// passwordResetAllowed reads the allow_password_reset policy
+func passwordResetAllowed(ctx context.Context) bool {
+	return policies.Bool(ctx, "allow_password_reset", true)
+}
+
+// POST /forgot-password
+// Always answers the same way so it cannot be used to probe for accounts
+func ForgotPassword(c *fiber.Ctx) error {
+	ctx := context.Background()
+	if !passwordResetAllowed(ctx) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Password reset is disabled"})
+	}
+
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	var userID int
+	var email string
+	err := db.DB.QueryRow(ctx, "SELECT id, email FROM users WHERE email = $1 AND is_active = TRUE;", req.Email).
+		Scan(&userID, &email)
+	if err == nil {
+		err := inTx(ctx, func(tx pgx.Tx) error {
+			return sendPasswordResetEmail(ctx, tx, userID, email)
+		})
+		if err != nil {
+			log.Printf("❌ Failed to send password reset email to user %d: %v", userID, err)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// POST /reset-password
+func ResetPassword(c *fiber.Ctx) error {
+	ctx := context.Background()
+	if !passwordResetAllowed(ctx) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Password reset is disabled"})
+	}
+
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" || req.Password == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	// Check the new password first so a rejected one does not burn the token
+	userID, err := usertokens.Peek(ctx, req.Token, usertokens.PurposePasswordReset)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset token"})
+	}
+	var email string
+	if err := db.DB.QueryRow(ctx, "SELECT email FROM users WHERE id = $1;", userID).Scan(&email); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset token"})
+	}
+	if violations, err := passwordpolicy.Check(ctx, req.Password, email); err != nil {
+		return policyUnavailable(c, err)
+	} else if len(violations) > 0 {
+		return weakPassword(c, violations)
+	}
+	if violations, err := passwordReuse(ctx, userID, req.Password); err != nil {
+		return hashingError(c, err, "Failed to check password history")
+	} else if len(violations) > 0 {
+		return weakPassword(c, violations)
+	}
+
+	hash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return hashingError(c, err, "Failed to hash password")
+	}
+
+	// The token is only used up if the new password is stored
+	err = inTx(ctx, func(tx pgx.Tx) error {
+		if _, err := usertokens.Consume(ctx, tx, req.Token, usertokens.PurposePasswordReset); err != nil {
+			return err
+		}
+		// The reset link reached the mailbox, which also proves the address
+		_, err := tx.Exec(ctx, `
+			UPDATE users
+			SET password_hash = $1, password_changed_at = NOW(),
+			    email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+			WHERE id = $2;
+		`, hash, userID)
+		return err
+	})
+	if errors.Is(err, usertokens.ErrInvalidToken) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired reset token"})
+	}
+	if err != nil {
+		log.Printf("❌ Failed to reset password for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset password"})
+	}
+	recordPasswordHistory(ctx, userID, hash)
+
+	// Whoever held the old password loses every session
+	if err := revocation.RevokeAllForUser(ctx, userID); err != nil {
+		log.Printf("❌ Failed to revoke tokens for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke user tokens"})
+	}
+	if err := usertokens.Invalidate(ctx, userID, usertokens.PurposePasswordReset); err != nil {
+		log.Printf("⚠️  Failed to invalidate reset tokens for user %d: %v", userID, err)
+	}
+	if err := lockout.Unlock(ctx, email); err != nil {
+		log.Printf("⚠️  Failed to clear login lockout for user %d: %v", userID, err)
+	}
+
+	log.Printf("🔑 Password reset for user %d", userID)
+	return c.JSON(fiber.Map{"message": "Password reset successfully"})
+}
+
+// sendPasswordResetEmail issues a reset token and queues its link; pass a
+// transaction so both are stored or neither is
+func sendPasswordResetEmail(ctx context.Context, tx pgx.Tx, userID int, email string) error {
+	ttl := policies.Duration(ctx, "password_reset_ttl", time.Hour)
+	token, err := usertokens.Issue(ctx, tx, userID, usertokens.PurposePasswordReset, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(config.Env("APP_BASE_URL", "http://localhost:8080"), "/") +
+		"/reset-password?token=" + url.QueryEscape(token)
+	return mailer.Queue(ctx, tx, "password_reset", email, mailLink{Link: link, ExpiresIn: mailer.HumanDuration(ttl)})
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"auth-service/internal/authz"
+	"auth-service/internal/db"
+	rolespkg "auth-service/internal/roles"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ✅ GET /admin/permissions
+func GetPermissions(c *fiber.Ctx) error {
+	ctx := context.Background()
+	rows, err := db.DB.Query(ctx, "SELECT id, name, COALESCE(description, '') FROM permissions ORDER BY name;")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch permissions"})
+	}
+	defer rows.Close()
+
+	permissions := []fiber.Map{}
+	for rows.Next() {
+		var id int
+		var name, desc string
+		rows.Scan(&id, &name, &desc)
+		permissions = append(permissions, fiber.Map{
+			"id":          id,
+			"name":        name,
+			"description": desc,
+		})
+	}
+
+	return c.JSON(fiber.Map{"permissions": permissions})
+}
+
+// ✅ POST /admin/permissions
+func CreatePermission(c *fiber.Ctx) error {
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON payload"})
+	}
+	if !rolespkg.ValidPermissionName(body.Name) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Permission name must look like resource:action"})
+	}
+
+	ctx := context.Background()
+	_, err := db.DB.Exec(ctx, `
+		INSERT INTO permissions (name, description)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description;
+	`, body.Name, body.Description)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create permission"})
+	}
+
+	log.Printf("✅ Permission created: %s", body.Name)
+	return c.JSON(fiber.Map{"message": "Permission created successfully", "permission": body})
+}
+
+// ✅ DELETE /admin/permissions/:name
+func DeletePermission(c *fiber.Ctx) error {
+	name := c.Params("name")
+
+	ctx := context.Background()
+	tag, err := db.DB.Exec(ctx, "DELETE FROM permissions WHERE name=$1;", name)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete permission"})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Permission not found"})
+	}
+
+	authz.InvalidateAll()
+
+	log.Printf("🗑️  Deleted permission %s", name)
+	return c.JSON(fiber.Map{"message": "Permission deleted successfully"})
+}
+
+// ✅ GET /admin/roles/:name/permissions
+func GetRolePermissions(c *fiber.Ctx) error {
+	role := c.Params("name")
+
+	ctx := context.Background()
+	var roleID int
+	if err := db.DB.QueryRow(ctx, "SELECT id FROM roles WHERE name=$1;", role).Scan(&roleID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role not found"})
+	}
+
+	rows, err := db.DB.Query(ctx, `
+		SELECT p.name FROM permissions p
+		JOIN role_permissions rp ON rp.permission_id = p.id
+		WHERE rp.role_id = $1
+		ORDER BY p.name;
+	`, roleID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch role permissions"})
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		permissions = append(permissions, name)
+	}
+
+	return c.JSON(fiber.Map{"role": role, "permissions": permissions})
+}
+
+// ✅ POST /admin/roles/:name/permissions
+func GrantRolePermission(c *fiber.Ctx) error {
+	role := c.Params("name")
+
+	var body struct {
+		Permission string `json:"permission"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON payload"})
+	}
+
+	ctx := context.Background()
+	var roleID, permissionID int
+	if err := db.DB.QueryRow(ctx, "SELECT id FROM roles WHERE name=$1;", role).Scan(&roleID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role not found"})
+	}
+	if err := db.DB.QueryRow(ctx, "SELECT id FROM permissions WHERE name=$1;", body.Permission).Scan(&permissionID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Permission not found"})
+	}
+
+	_, err := db.DB.Exec(ctx, `
+		INSERT INTO role_permissions (role_id, permission_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;
+	`, roleID, permissionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to grant permission"})
+	}
+
+	authz.InvalidateAll()
+
+	log.Printf("✅ Granted permission %s to role %s", body.Permission, role)
+	return c.JSON(fiber.Map{"message": "Permission granted successfully"})
+}
+
+// ✅ DELETE /admin/roles/:name/permissions/:permission
+func RevokeRolePermission(c *fiber.Ctx) error {
+	role := c.Params("name")
+	permission := c.Params("permission")
+
+	ctx := context.Background()
+	tag, err := db.DB.Exec(ctx, `
+		DELETE FROM role_permissions rp
+		USING roles r, permissions p
+		WHERE rp.role_id = r.id AND rp.permission_id = p.id
+		  AND r.name = $1 AND p.name = $2;
+	`, role, permission)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke permission"})
+	}
+	if tag.RowsAffected() == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role does not have this permission"})
+	}
+
+	authz.InvalidateAll()
+
+	log.Printf("🚫 Revoked permission %s from role %s", permission, role)
+	return c.JSON(fiber.Map{"message": "Permission revoked successfully"})
+}
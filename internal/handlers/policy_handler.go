@@ -4,24 +4,15 @@ import (
 	"context"
 	"log"
 
+	"auth-service/internal/authz"
 	"auth-service/internal/db"
-	jwtpkg "auth-service/pkg/jwt"
+	"auth-service/internal/policies"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // ✅ GET /superadmin/policies
 func GetAllPolicies(c *fiber.Ctx) error {
-	user := c.Locals("user")
-	if user == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
-
-	claims := user.(*jwtpkg.CustomClaims)
-	if !hasRole(claims.Roles, "super_admin") {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only super_admin can view policies"})
-	}
-
 	ctx := context.Background()
 	rows, err := db.DB.Query(ctx, "SELECT name, value FROM auth_policies ORDER BY id;")
 	if err != nil {
@@ -41,16 +32,6 @@ func GetAllPolicies(c *fiber.Ctx) error {
 
 // ✅ GET /superadmin/policies/:name
 func GetPolicyByName(c *fiber.Ctx) error {
-	user := c.Locals("user")
-	if user == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
-
-	claims := user.(*jwtpkg.CustomClaims)
-	if !hasRole(claims.Roles, "super_admin") {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only super_admin can view policy"})
-	}
-
 	name := c.Params("name")
 	ctx := context.Background()
 
@@ -65,16 +46,6 @@ func GetPolicyByName(c *fiber.Ctx) error {
 
 // ✅ POST /superadmin/policies
 func UpsertPolicies(c *fiber.Ctx) error {
-	user := c.Locals("user")
-	if user == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
-
-	claims := user.(*jwtpkg.CustomClaims)
-	if !hasRole(claims.Roles, "super_admin") {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only super_admin can modify policies"})
-	}
-
 	body := make(map[string]string)
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON body"})
@@ -94,6 +65,9 @@ func UpsertPolicies(c *fiber.Ctx) error {
 		}
 	}
 
+	policies.Invalidate()
+	authz.InvalidateAll()
+
 	return c.JSON(fiber.Map{
 		"message":  "Policies updated successfully",
 		"policies": body,
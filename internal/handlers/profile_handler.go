@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+
+	"auth-service/internal/audit"
+	"auth-service/internal/profile"
+	jwtpkg "auth-service/pkg/jwt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// ✅ PATCH /me
+// Updates the caller's display_name, locale, timezone, avatar_url and
+// metadata.user; omitted fields are kept, null clears a field
+func UpdateMe(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	patch, err := profile.ParsePatch(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if patch.AdminMetadata != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": profile.ErrAdminOnly.Error()})
+	}
+
+	return updateProfile(c, claims.UserID, claims.UserID, patch)
+}
+
+// ✅ PATCH /admin/users/:id
+// Same fields as PATCH /me, plus metadata.admin
+func UpdateUserProfile(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	patch, err := profile.ParsePatch(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Profile fields and metadata.admin end up in the user's tokens, which
+	// downstream services trust as admin-asserted
+	if userID != claims.UserID {
+		if ok, err := requireRank(context.Background(), c, userID, claims.UserID, "update the profile of"); !ok {
+			return err
+		}
+	}
+
+	return updateProfile(c, userID, claims.UserID, patch)
+}
+
+// updateProfile applies a validated patch and records who changed which fields
+func updateProfile(c *fiber.Ctx, userID, actorID int, patch *profile.Patch) error {
+	ctx := context.Background()
+	p, err := profile.Update(ctx, userID, patch)
+	if err != nil {
+		return profileError(c, userID, err)
+	}
+
+	metadata := map[string]any{"fields": patch.Fields()}
+	if actorID != userID {
+		metadata["by"] = actorID
+	}
+	if err := audit.Record(ctx, userID, "profile_updated", metadata); err != nil {
+		log.Printf("⚠️  Failed to write audit log for user %d: %v", userID, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Profile updated",
+		"profile": p,
+	})
+}
+
+// profileError maps profile load / update failures to responses
+func profileError(c *fiber.Ctx, userID int, err error) error {
+	var invalid *profile.ValidationError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	case errors.As(err, &invalid):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": invalid.Error()})
+	default:
+		log.Printf("❌ Profile error for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+}
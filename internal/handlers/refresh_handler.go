@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"auth-service/internal/db"
+	"auth-service/internal/profile"
+	"auth-service/internal/utils"
+	jwtpkg "auth-service/pkg/jwt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// RefreshRequest defines incoming payload for /refresh
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// querier is satisfied by both the pool and a transaction
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// newSessionID starts a new refresh token family; it doubles as the sid claim
+func newSessionID() (string, error) {
+	return utils.GenerateRandomToken(16)
+}
+
+// issueRefreshToken stores the hash of a new refresh token in the given family
+// and returns the raw token and its row id. The audience is remembered so
+// rotated access tokens are minted for the same client.
+func issueRefreshToken(ctx context.Context, q querier, userID int, familyID, audience string) (string, int, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var id int
+	err = q.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (user_id, token, family_id, audience, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id;
+	`, userID, utils.HashToken(token), familyID, audience, time.Now().UTC().Add(refreshTokenTTL(ctx))).Scan(&id)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return token, id, nil
+}
+
+// revokeTokenFamily invalidates every refresh token descending from the same login
+func revokeTokenFamily(ctx context.Context, q querier, familyID string) error {
+	_, err := q.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW()
+		WHERE family_id = $1 AND revoked = FALSE;
+	`, familyID)
+	return err
+}
+
+// POST /refresh
+func Refresh(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request payload",
+		})
+	}
+
+	ctx := context.Background()
+	tx, err := db.DB.Begin(ctx)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the presented token so concurrent refreshes cannot both rotate it
+	var tokenID, userID int
+	var familyID, audience string
+	var expiresAt time.Time
+	var revoked bool
+	var replacedBy *int
+	err = tx.QueryRow(ctx, `
+		SELECT id, user_id, family_id, COALESCE(audience, ''), expires_at, revoked, replaced_by
+		FROM refresh_tokens
+		WHERE token = $1
+		FOR UPDATE;
+	`, utils.HashToken(req.RefreshToken)).Scan(&tokenID, &userID, &familyID, &audience, &expiresAt, &revoked, &replacedBy)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("❌ Failed to look up refresh token: %v", err)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+
+	if revoked {
+		// A rotated token being replayed means it leaked: kill the whole family
+		if replacedBy != nil {
+			log.Printf("🚨 Refresh token reuse detected for user %d, revoking family %s", userID, familyID)
+			if err := revokeTokenFamily(ctx, tx, familyID); err != nil {
+				log.Printf("❌ Failed to revoke token family %s: %v", familyID, err)
+			} else if err := tx.Commit(ctx); err != nil {
+				log.Printf("❌ Failed to revoke token family %s: %v", familyID, err)
+			}
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+
+	if time.Now().UTC().After(expiresAt) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token expired"})
+	}
+
+	// The audience may have been removed from allowed_audiences since login
+	audience, ok := resolveAudience(ctx, audience)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Audience is no longer allowed"})
+	}
+
+	var email string
+	var isActive bool
+	err = tx.QueryRow(ctx, "SELECT email, is_active FROM users WHERE id=$1;", userID).Scan(&email, &isActive)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid refresh token"})
+	}
+	if !isActive {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User account is inactive"})
+	}
+
+	roles, err := loadUserRoles(ctx, tx, userID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load roles: %v", err)
+	}
+
+	// Rotate: issue a successor in the same family and retire the presented token
+	newToken, newID, err := issueRefreshToken(ctx, tx, userID, familyID, audience)
+	if err != nil {
+		log.Printf("❌ Failed to issue refresh token: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rotate refresh token"})
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW(), replaced_by = $1
+		WHERE id = $2;
+	`, newID, tokenID)
+	if err != nil {
+		log.Printf("❌ Failed to retire refresh token %d: %v", tokenID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rotate refresh token"})
+	}
+
+	// Metadata changes reach the token on the next refresh
+	metadata, err := profile.Claims(ctx, userID)
+	if err != nil {
+		log.Printf("❌ Failed to load metadata claims for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate access token"})
+	}
+
+	accessToken, err := jwtpkg.GenerateAccessToken(userID, email, roles, jwtpkg.TokenOptions{
+		SessionID: familyID,
+		Audience:  audience,
+		TTL:       accessTokenTTL(ctx, audience),
+		Metadata:  metadata,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate access token"})
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to rotate refresh token"})
+	}
+
+	return c.JSON(fiber.Map{
+		"access_token":  accessToken,
+		"refresh_token": newToken,
+	})
+}
@@ -2,16 +2,22 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"log"
 	"strings"
 
 	"auth-service/internal/db"
+	"auth-service/internal/passwordpolicy"
 	"auth-service/internal/utils"
 	jwtpkg "auth-service/pkg/jwt"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
 )
 
+// errEmailTaken aborts a registration whose user row could not be inserted
+var errEmailTaken = errors.New("email already exists or invalid")
+
 // RegisterRequest – expected request body
 type RegisterRequest struct {
 	Email    string `json:"email"`
@@ -83,26 +89,50 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
-	// 3️⃣  Hash password
+	// 3️⃣  Enforce the password policy
+	if violations, err := passwordpolicy.Check(ctx, req.Password, req.Email); err != nil {
+		return policyUnavailable(c, err)
+	} else if len(violations) > 0 {
+		return weakPassword(c, violations)
+	}
+
+	// 4️⃣  Hash password
 	hash, err := utils.HashPassword(req.Password)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to hash password",
-		})
+		return hashingError(c, err, "Failed to hash password")
 	}
 
-	// 4️⃣  Insert new user
+	// 5️⃣  Insert new user and, with email verification on, queue the
+	// verification mail in the same transaction (the account cannot log in
+	// until verified)
+	verificationRequired := emailVerificationRequired(ctx)
 	var userID int
-	err = db.DB.QueryRow(ctx,
-		"INSERT INTO users (email, password_hash, is_active, created_at, updated_at) VALUES ($1, $2, TRUE, NOW(), NOW()) RETURNING id;",
-		req.Email, hash).Scan(&userID)
-	if err != nil {
+	err = inTx(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			"INSERT INTO users (email, password_hash, is_active, created_at, updated_at) VALUES ($1, $2, TRUE, NOW(), NOW()) RETURNING id;",
+			req.Email, hash).Scan(&userID)
+		if err != nil {
+			return errEmailTaken
+		}
+		if verificationRequired {
+			return sendVerificationEmail(ctx, tx, userID, req.Email)
+		}
+		return nil
+	})
+	if errors.Is(err, errEmailTaken) {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 			"error": "Email already exists or invalid",
 		})
 	}
+	if err != nil {
+		log.Printf("❌ Failed to register %s: %v", req.Email, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to register user",
+		})
+	}
 
 	log.Printf("✅ Registered new user: %s (id=%d)", req.Email, userID)
+	recordPasswordHistory(ctx, userID, hash)
 
 	return c.JSON(fiber.Map{
 		"message": "User registered successfully",
@@ -110,6 +140,7 @@ func Register(c *fiber.Ctx) error {
 			"id":    userID,
 			"email": req.Email,
 		},
+		"email_verification_required": verificationRequired,
 	})
 }
 
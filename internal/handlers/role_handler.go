@@ -2,59 +2,56 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"log"
 
+	"auth-service/internal/authz"
 	"auth-service/internal/db"
-	jwtpkg "auth-service/pkg/jwt"
+	"auth-service/internal/listing"
+	rolespkg "auth-service/internal/roles"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// roleListing backs GET /admin/roles
+var roleListing = listing.Spec{
+	Select: `r.id, r.name, COALESCE(r.description, ''),
+		ARRAY(SELECT p.name FROM role_parents rp JOIN roles p ON p.id = rp.parent_id
+		      WHERE rp.role_id = r.id ORDER BY p.name)`,
+	From: "roles r",
+	Key:  "r.id",
+	Sorts: map[string]listing.Column{
+		"id":   {Expr: "r.id", Type: "bigint"},
+		"name": {Expr: "r.name", Type: "text"},
+	},
+	DefaultSort: "id",
+	Filters: map[string]listing.Filter{
+		"name_prefix":   listing.Prefix("r.name"),
+		"name_contains": listing.Contains("r.name"),
+	},
+}
+
 // ✅ GET /admin/roles
+// Paginated: ?limit=&cursor=&sort=name&name_prefix=&name_contains=
 func GetRoles(c *fiber.Ctx) error {
-	user := c.Locals("user")
-	if user == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
-
-	claims := user.(*jwtpkg.CustomClaims)
-	if !hasRole(claims.Roles, "super_admin") {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only super_admin can view roles"})
-	}
-
-	ctx := context.Background()
-	rows, err := db.DB.Query(ctx, "SELECT id, name, description FROM roles ORDER BY id;")
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch roles"})
-	}
-	defer rows.Close()
-
-	var roles []fiber.Map
-	for rows.Next() {
+	return listPage(c, roleListing, "roles", func(scan func(dest ...any) error) (fiber.Map, error) {
 		var id int
 		var name, desc string
-		rows.Scan(&id, &name, &desc)
-		roles = append(roles, fiber.Map{
+		var parents []string
+		if err := scan(&id, &name, &desc, &parents); err != nil {
+			return nil, err
+		}
+		return fiber.Map{
 			"id":          id,
 			"name":        name,
 			"description": desc,
-		})
-	}
-
-	return c.JSON(fiber.Map{"roles": roles})
+			"parents":     parents,
+		}, nil
+	})
 }
 
 // ✅ POST /admin/roles
 func CreateRole(c *fiber.Ctx) error {
-	user := c.Locals("user")
-	if user == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
-	claims := user.(*jwtpkg.CustomClaims)
-	if !hasRole(claims.Roles, "super_admin") {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only super_admin can create roles"})
-	}
-
 	var body struct {
 		Name        string `json:"name"`
 		Description string `json:"description"`
@@ -79,15 +76,6 @@ func CreateRole(c *fiber.Ctx) error {
 
 // ✅ POST /admin/assign-role
 func AssignRole(c *fiber.Ctx) error {
-	user := c.Locals("user")
-	if user == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
-	claims := user.(*jwtpkg.CustomClaims)
-	if !hasRole(claims.Roles, "super_admin") {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only super_admin can assign roles"})
-	}
-
 	var body struct {
 		UserID int    `json:"user_id"`
 		Role   string `json:"role"`
@@ -112,21 +100,14 @@ func AssignRole(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to assign role"})
 	}
 
+	authz.InvalidateUser(body.UserID)
+
 	log.Printf("✅ Assigned role %s to user %d", body.Role, body.UserID)
 	return c.JSON(fiber.Map{"message": "Role assigned successfully"})
 }
 
 // ✅ DELETE /admin/revoke-role
 func RevokeRole(c *fiber.Ctx) error {
-	user := c.Locals("user")
-	if user == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
-	claims := user.(*jwtpkg.CustomClaims)
-	if !hasRole(claims.Roles, "super_admin") {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only super_admin can revoke roles"})
-	}
-
 	var body struct {
 		UserID int    `json:"user_id"`
 		Role   string `json:"role"`
@@ -149,6 +130,91 @@ func RevokeRole(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke role"})
 	}
 
+	authz.InvalidateUser(body.UserID)
+
 	log.Printf("🚫 Revoked role %s from user %d", body.Role, body.UserID)
 	return c.JSON(fiber.Map{"message": "Role revoked successfully"})
 }
+
+// ✅ POST /admin/roles/:name/parents
+// The role inherits every permission of the parent (e.g. super_admin → admin)
+func AddRoleParent(c *fiber.Ctx) error {
+	role := c.Params("name")
+
+	var body struct {
+		Parent string `json:"parent"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON payload"})
+	}
+
+	err := rolespkg.AddParent(context.Background(), role, body.Parent)
+	switch {
+	case errors.Is(err, rolespkg.ErrRoleNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role not found"})
+	case errors.Is(err, rolespkg.ErrCycle):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Parent would create a cycle in the role hierarchy"})
+	case err != nil:
+		log.Printf("❌ Failed to add parent %s to role %s: %v", body.Parent, role, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to add parent role"})
+	}
+
+	authz.InvalidateAll()
+
+	log.Printf("✅ Role %s now inherits from %s", role, body.Parent)
+	return c.JSON(fiber.Map{"message": "Parent role added successfully"})
+}
+
+// ✅ DELETE /admin/roles/:name/parents/:parent
+func RemoveRoleParent(c *fiber.Ctx) error {
+	role := c.Params("name")
+	parent := c.Params("parent")
+
+	removed, err := rolespkg.RemoveParent(context.Background(), role, parent)
+	if err != nil {
+		log.Printf("❌ Failed to remove parent %s from role %s: %v", parent, role, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to remove parent role"})
+	}
+	if !removed {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role does not inherit from this parent"})
+	}
+
+	authz.InvalidateAll()
+
+	log.Printf("🚫 Role %s no longer inherits from %s", role, parent)
+	return c.JSON(fiber.Map{"message": "Parent role removed successfully"})
+}
+
+// holdsRoleNotHeldBy reports whether target has an effective role that
+// caller lacks
+func holdsRoleNotHeldBy(ctx context.Context, target, caller int) (bool, error) {
+	targetRoles, err := rolespkg.EffectiveRoles(ctx, db.DB, target)
+	if err != nil {
+		return false, err
+	}
+	callerRoles, err := rolespkg.EffectiveRoles(ctx, db.DB, caller)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range targetRoles {
+		if !hasRole(callerRoles, r) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// requireRank lets an admin act on another account only if they hold every
+// role it holds. It answers the request itself and returns false otherwise.
+func requireRank(ctx context.Context, c *fiber.Ctx, target, caller int, action string) (bool, error) {
+	outranked, err := holdsRoleNotHeldBy(ctx, target, caller)
+	if err != nil {
+		log.Printf("❌ Failed to compare roles of users %d and %d: %v", target, caller, err)
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify roles"})
+	}
+	if outranked {
+		log.Printf("🚫 User %d tried to %s user %d, who holds roles they do not", caller, action, target)
+		return false, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "User holds roles you do not have"})
+	}
+	return true, nil
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/policies"
+	jwtpkg "auth-service/pkg/jwt"
+)
+
+// resolveAudience returns the audience a token should be minted for and
+// whether this service is allowed to issue tokens for it. The service's own
+// audience is always allowed; others must be listed in allowed_audiences.
+func resolveAudience(ctx context.Context, requested string) (string, bool) {
+	own := jwtpkg.Settings().Audience
+	if requested == "" || requested == own {
+		return own, true
+	}
+	for _, aud := range policies.StringSlice(ctx, "allowed_audiences", nil) {
+		if aud == requested {
+			return requested, true
+		}
+	}
+	return "", false
+}
+
+// accessTokenTTL reads the access_token_ttl policy, which is either a single
+// duration ("1h") or per-audience durations ({"default":"1h","billing":"15m"})
+func accessTokenTTL(ctx context.Context, audience string) time.Duration {
+	fallback := jwtpkg.Settings().AccessTokenTTL
+
+	var perAudience map[string]string
+	if policies.Decode(ctx, "access_token_ttl", &perAudience) {
+		if d, ok := policies.ParseDuration(perAudience[audience]); ok {
+			return d
+		}
+		if d, ok := policies.ParseDuration(perAudience["default"]); ok {
+			return d
+		}
+		return fallback
+	}
+
+	return policies.Duration(ctx, "access_token_ttl", fallback)
+}
+
+// refreshTokenTTL reads the refresh_token_ttl policy, falling back to
+// REFRESH_TOKEN_EXPIRY_DAYS (default 7 days)
+func refreshTokenTTL(ctx context.Context) time.Duration {
+	fallback := time.Duration(config.EnvInt("REFRESH_TOKEN_EXPIRY_DAYS", 7)) * 24 * time.Hour
+	return policies.Duration(ctx, "refresh_token_ttl", fallback)
+}
@@ -6,45 +6,47 @@ import (
 	"strconv"
 	"time"
 
+	"auth-service/internal/audit"
+	"auth-service/internal/authz"
 	"auth-service/internal/db"
+	"auth-service/internal/listing"
+	"auth-service/internal/lockout"
+	"auth-service/internal/profile"
+	"auth-service/internal/revocation"
 	jwtpkg "auth-service/pkg/jwt"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// ✅ GET /admin/users
+// userListing backs GET /admin/users
+var userListing = listing.Spec{
+	Select: `u.id, u.email, u.is_active, u.created_at,
+		ARRAY(SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+		      WHERE ur.user_id = u.id ORDER BY r.name)`,
+	From: "users u",
+	Key:  "u.id",
+	Sorts: map[string]listing.Column{
+		"id":         {Expr: "u.id", Type: "bigint"},
+		"email":      {Expr: "u.email", Type: "text"},
+		"created_at": {Expr: "u.created_at", Type: "timestamp"},
+	},
+	DefaultSort: "id",
+	Filters: map[string]listing.Filter{
+		"is_active":      listing.Equals("u.is_active", listing.Bool),
+		"created_after":  listing.TimeRange("u.created_at", true),
+		"created_before": listing.TimeRange("u.created_at", false),
+		"email_prefix":   listing.Prefix("u.email"),
+		"email_contains": listing.Contains("u.email"),
+		"role": func(value string, arg func(any) string) (string, error) {
+			return `EXISTS (SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = u.id AND r.name = ` + arg(value) + ")", nil
+		},
+	},
+}
 
+// ✅ GET /admin/users
+// Paginated: ?limit=&cursor=&sort=-created_at&is_active=&role=&created_after=&created_before=&email_prefix=&email_contains=
 func ListUsers(c *fiber.Ctx) error {
-	user := c.Locals("user")
-	if user == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
-
-	claims := user.(*jwtpkg.CustomClaims)
-	if !(hasRole(claims.Roles, "super_admin") || hasRole(claims.Roles, "admin")) {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
-	}
-
-	ctx := context.Background()
-	rows, err := db.DB.Query(ctx, `
-		SELECT 
-			u.id, 
-			u.email, 
-			u.is_active, 
-			u.created_at, 
-			COALESCE(array_agg(r.name) FILTER (WHERE r.name IS NOT NULL), '{}') AS roles
-		FROM users u
-		LEFT JOIN user_roles ur ON u.id = ur.user_id
-		LEFT JOIN roles r ON ur.role_id = r.id
-		GROUP BY u.id
-		ORDER BY u.id;
-	`)
-	if err != nil {
-		log.Printf("❌ Error fetching users: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
-	}
-	defer rows.Close()
-
 	type userResp struct {
 		ID        int       `json:"id"`
 		Email     string    `json:"email"`
@@ -53,34 +55,15 @@ func ListUsers(c *fiber.Ctx) error {
 		Roles     []string  `json:"roles"`
 	}
 
-	var users []userResp
-
-	for rows.Next() {
+	return listPage(c, userListing, "users", func(scan func(dest ...any) error) (userResp, error) {
 		var u userResp
-		err := rows.Scan(&u.ID, &u.Email, &u.IsActive, &u.CreatedAt, &u.Roles)
-		if err != nil {
-			log.Printf("⚠️  Scan error: %v", err)
-			continue
-		}
-		users = append(users, u)
-	}
-
-	return c.JSON(fiber.Map{"users": users})
+		err := scan(&u.ID, &u.Email, &u.IsActive, &u.CreatedAt, &u.Roles)
+		return u, err
+	})
 }
 
-
 // ✅ GET /admin/users/:id
 func GetUserByID(c *fiber.Ctx) error {
-	user := c.Locals("user")
-	if user == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
-
-	claims := user.(*jwtpkg.CustomClaims)
-	if !(hasRole(claims.Roles, "super_admin") || hasRole(claims.Roles, "admin")) {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
-	}
-
 	idParam := c.Params("id")
 	userID, err := strconv.Atoi(idParam)
 	if err != nil {
@@ -123,29 +106,31 @@ func GetUserByID(c *fiber.Ctx) error {
 		roles = append(roles, roleName)
 	}
 
+	// Roles inherited through the hierarchy
+	effectiveRoles, err := loadUserRoles(ctx, db.DB, userID)
+	if err != nil {
+		log.Printf("⚠️  Failed to resolve effective roles: %v", err)
+	}
+
+	p, err := profile.Get(ctx, userID)
+	if err != nil {
+		return profileError(c, userID, err)
+	}
+
 	// ✅ Response
 	return c.JSON(fiber.Map{
-		"id":         userID,
-		"email":      email,
-		"is_active":  isActive,
-		"created_at": createdAt,
-		"roles":      roles,
+		"id":              userID,
+		"email":           email,
+		"is_active":       isActive,
+		"created_at":      createdAt,
+		"roles":           roles,
+		"effective_roles": effectiveRoles,
+		"profile":         p,
 	})
 }
 
-
 // ✅ PATCH /admin/users/:id/status
 func UpdateUserStatus(c *fiber.Ctx) error {
-	user := c.Locals("user")
-	if user == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
-
-	claims := user.(*jwtpkg.CustomClaims)
-	if !(hasRole(claims.Roles, "super_admin") || hasRole(claims.Roles, "admin")) {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
-	}
-
 	idParam := c.Params("id")
 	userID, err := strconv.Atoi(idParam)
 	if err != nil {
@@ -165,34 +150,92 @@ func UpdateUserStatus(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update user status"})
 	}
 
+	// Deactivated users lose every token they currently hold
+	if !body.IsActive {
+		if err := revocation.RevokeAllForUser(ctx, userID); err != nil {
+			log.Printf("❌ Failed to revoke tokens for user %d: %v", userID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to revoke user tokens"})
+		}
+	}
+
+	authz.InvalidateUser(userID)
+
 	log.Printf("🔄 Updated user %d status to %v", userID, body.IsActive)
 	return c.JSON(fiber.Map{"message": "User status updated successfully"})
 }
 
-// ✅ DELETE /admin/users/:id
-func DeleteUser(c *fiber.Ctx) error {
-	user := c.Locals("user")
-	if user == nil {
+// ✅ POST /admin/users/:id/unlock
+// Lifts a brute-force lockout. An optional {"ip": "..."} also clears that source IP.
+func UnlockUser(c *fiber.Ctx) error {
+	idParam := c.Params("id")
+	userID, err := strconv.Atoi(idParam)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+	claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	claims := user.(*jwtpkg.CustomClaims)
-	if !hasRole(claims.Roles, "super_admin") {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only super_admin can delete users"})
+	var body struct {
+		IP string `json:"ip"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON payload"})
+		}
 	}
 
+	ctx := context.Background()
+	var email string
+	if err := db.DB.QueryRow(ctx, "SELECT email FROM users WHERE id=$1;", userID).Scan(&email); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if ok, err := requireRank(ctx, c, userID, claims.UserID, "unlock"); !ok {
+		return err
+	}
+
+	if err := lockout.Unlock(ctx, email); err != nil {
+		log.Printf("❌ Failed to unlock user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to unlock user"})
+	}
+	if body.IP != "" {
+		if err := lockout.UnlockIP(ctx, body.IP); err != nil {
+			log.Printf("❌ Failed to unlock IP %s: %v", body.IP, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to unlock IP"})
+		}
+	}
+
+	metadata := map[string]any{"by": claims.UserID}
+	if body.IP != "" {
+		metadata["ip"] = body.IP
+	}
+	if err := audit.Record(ctx, userID, "user_unlocked", metadata); err != nil {
+		log.Printf("⚠️  Failed to write audit log for user %d: %v", userID, err)
+	}
+
+	log.Printf("🔓 Unlocked user %d", userID)
+	return c.JSON(fiber.Map{"message": "User unlocked successfully"})
+}
+
+// ✅ DELETE /admin/users/:id
+func DeleteUser(c *fiber.Ctx) error {
 	idParam := c.Params("id")
 	userID, err := strconv.Atoi(idParam)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
 	}
 
+	// Refresh tokens cascade with the user row; access tokens are rejected
+	// by the middleware once the user no longer exists
 	ctx := context.Background()
 	_, err = db.DB.Exec(ctx, "DELETE FROM users WHERE id=$1;", userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete user"})
 	}
 
+	authz.InvalidateUser(userID)
+
 	log.Printf("🗑️  Deleted user %d", userID)
 	return c.JSON(fiber.Map{"message": "User deleted successfully"})
 }
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/db"
+	"auth-service/internal/mailer"
+	"auth-service/internal/policies"
+	"auth-service/internal/usertokens"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// VerifyEmailRequest defines incoming payload for /verify-email
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ResendVerificationRequest defines incoming payload for /resend-verification
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// mailLink is the template data of mails carrying a single-use link
+type mailLink struct {
+	Link      string
+	ExpiresIn string
+}
+
+// emailVerificationRequired reads the require_email_verification policy
+func emailVerificationRequired(ctx context.Context) bool {
+	return policies.Bool(ctx, "require_email_verification", false)
+}
+
+// emailUnverified reports whether the user may not log in yet because
+// require_email_verification is on and their address is unverified
+func emailUnverified(ctx context.Context, userID int) (bool, error) {
+	if !emailVerificationRequired(ctx) {
+		return false, nil
+	}
+	var verifiedAt *time.Time
+	err := db.DB.QueryRow(ctx, "SELECT email_verified_at FROM users WHERE id = $1;", userID).Scan(&verifiedAt)
+	if err != nil {
+		return false, err
+	}
+	return verifiedAt == nil, nil
+}
+
+// sendVerificationEmail issues a fresh verification token and queues its
+// link; pass a transaction so both are stored or neither is
+func sendVerificationEmail(ctx context.Context, tx pgx.Tx, userID int, email string) error {
+	ttl := policies.Duration(ctx, "email_verification_ttl", 24*time.Hour)
+	token, err := usertokens.Issue(ctx, tx, userID, usertokens.PurposeVerifyEmail, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(config.Env("APP_BASE_URL", "http://localhost:8080"), "/") +
+		"/api/v1/verify-email?token=" + url.QueryEscape(token)
+	return mailer.Queue(ctx, tx, "verify_email", email, mailLink{Link: link, ExpiresIn: mailer.HumanDuration(ttl)})
+}
+
+// GET|POST /verify-email
+func VerifyEmail(c *fiber.Ctx) error {
+	var req VerifyEmailRequest
+	if c.Method() == fiber.MethodPost {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+		}
+	} else {
+		req.Token = c.Query("token")
+	}
+	if req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Missing token"})
+	}
+
+	ctx := context.Background()
+	var userID int
+	err := inTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		if userID, err = usertokens.Consume(ctx, tx, req.Token, usertokens.PurposeVerifyEmail); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE users SET email_verified_at = NOW(), updated_at = NOW()
+			WHERE id = $1 AND email_verified_at IS NULL;
+		`, userID)
+		return err
+	})
+	if errors.Is(err, usertokens.ErrInvalidToken) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired verification token"})
+	}
+	if err != nil {
+		log.Printf("❌ Failed to mark email verified for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify email"})
+	}
+
+	log.Printf("✅ Verified email of user %d", userID)
+	return c.JSON(fiber.Map{"message": "Email verified successfully"})
+}
+
+// POST /resend-verification
+// Always answers the same way so it cannot be used to probe for accounts
+func ResendVerification(c *fiber.Ctx) error {
+	var req ResendVerificationRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request payload"})
+	}
+
+	ctx := context.Background()
+	var userID int
+	var email string
+	err := db.DB.QueryRow(ctx, `
+		SELECT id, email FROM users
+		WHERE email = $1 AND email_verified_at IS NULL AND is_active = TRUE;
+	`, req.Email).Scan(&userID, &email)
+	if err == nil {
+		err := inTx(ctx, func(tx pgx.Tx) error {
+			return sendVerificationEmail(ctx, tx, userID, email)
+		})
+		if err != nil {
+			log.Printf("❌ Failed to send verification email to user %d: %v", userID, err)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "If the account exists and is unverified, a verification email has been sent",
+	})
+}
+
+// inTx runs fn in a transaction and commits if it succeeds
+func inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := db.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
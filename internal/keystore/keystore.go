@@ -0,0 +1,267 @@
+package keystore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/db"
+	"auth-service/internal/utils"
+	jwtpkg "auth-service/pkg/jwt"
+)
+
+var (
+	// ErrKeyNotFound is returned when no unretired key matches a kid
+	ErrKeyNotFound = errors.New("signing key not found")
+	// ErrUnsupportedAlgorithm is returned by Generate for an unknown algorithm
+	ErrUnsupportedAlgorithm = jwtpkg.ErrUnsupportedAlgorithm
+)
+
+// KeyInfo describes a stored signing key without exposing its private part
+type KeyInfo struct {
+	KID         string     `json:"kid"`
+	Algorithm   string     `json:"alg"`
+	ActivatesAt time.Time  `json:"activates_at"`
+	RetiresAt   *time.Time `json:"retires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Status      string     `json:"status"`
+}
+
+// Generate creates a new signing key that starts signing at activatesAt.
+// The private key is encrypted with JWT_KEYSTORE_SECRET before it is stored.
+func Generate(ctx context.Context, alg string, activatesAt time.Time) (*KeyInfo, error) {
+	key, pemBytes, err := jwtpkg.GenerateSigningKey(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := seal(pemBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	info := KeyInfo{KID: key.ID, Algorithm: alg, ActivatesAt: activatesAt.UTC()}
+	err = db.DB.QueryRow(ctx, `
+		INSERT INTO signing_keys (kid, algorithm, private_key, activates_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at;
+	`, info.KID, info.Algorithm, sealed, info.ActivatesAt).Scan(&info.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Status = status(info, time.Now())
+	return &info, nil
+}
+
+// Promote makes a key the signing key immediately
+func Promote(ctx context.Context, kid string) error {
+	now := time.Now().UTC()
+	tag, err := db.DB.Exec(ctx, `
+		UPDATE signing_keys SET activates_at = $2
+		WHERE kid = $1 AND (retires_at IS NULL OR retires_at > $2);
+	`, kid, now)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// Retire schedules a key to stop verifying tokens at the given time.
+// Retire at least one access token lifetime after the key stops signing.
+func Retire(ctx context.Context, kid string, at time.Time) error {
+	tag, err := db.DB.Exec(ctx, `
+		UPDATE signing_keys SET retires_at = $2
+		WHERE kid = $1 AND (retires_at IS NULL OR retires_at > $2);
+	`, kid, at.UTC())
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// List returns metadata for every stored key, newest activation first
+func List(ctx context.Context) ([]KeyInfo, error) {
+	rows, err := db.DB.Query(ctx, `
+		SELECT kid, algorithm, activates_at, retires_at, created_at
+		FROM signing_keys
+		ORDER BY activates_at DESC;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	keys := []KeyInfo{}
+	for rows.Next() {
+		var k KeyInfo
+		if err := rows.Scan(&k.KID, &k.Algorithm, &k.ActivatesAt, &k.RetiresAt, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		k.Status = status(k, now)
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Mark which of the active keys is actually signing
+	for i := range keys {
+		if keys[i].Status == "active" {
+			keys[i].Status = "signing"
+			break
+		}
+	}
+	return keys, nil
+}
+
+// Reload rebuilds the JWT key ring from the database. A key configured via
+// JWT_PRIVATE_KEY_PATH / JWT_SECRET stays in the ring as the oldest key so
+// tokens issued before the first rotation remain valid, until it retires
+// (see envKeyRetirement).
+func Reload(ctx context.Context) error {
+	now := time.Now().UTC()
+	rows, err := db.DB.Query(ctx, `
+		SELECT kid, private_key, activates_at, retires_at
+		FROM signing_keys
+		WHERE retires_at IS NULL OR retires_at > $1;
+	`, now)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var keys []*jwtpkg.SigningKey
+	for rows.Next() {
+		var kid, sealed string
+		var activatesAt time.Time
+		var retiresAt *time.Time
+		if err := rows.Scan(&kid, &sealed, &activatesAt, &retiresAt); err != nil {
+			return err
+		}
+
+		pemBytes, err := open(sealed)
+		if err != nil {
+			return fmt.Errorf("decrypt signing key %s: %w", kid, err)
+		}
+		key, err := jwtpkg.ParsePrivateKeyPEM(pemBytes, kid)
+		if err != nil {
+			return fmt.Errorf("parse signing key %s: %w", kid, err)
+		}
+		key.ActivatesAt = activatesAt
+		if retiresAt != nil {
+			key.RetiresAt = *retiresAt
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if envKey, err := jwtpkg.KeyFromEnv(); err == nil {
+		retiresAt, err := envKeyRetirement(keys, now)
+		if err != nil {
+			return err
+		}
+		envKey.RetiresAt = retiresAt
+		if !envKey.IsRetired(now) {
+			keys = append(keys, envKey)
+		}
+	}
+
+	if len(keys) == 0 {
+		return errors.New("no signing keys configured")
+	}
+
+	jwtpkg.SetKeyRing(jwtpkg.NewKeyRing(keys...))
+	return nil
+}
+
+// envKeyRetirement decides when the environment key stops verifying tokens:
+// at JWT_ENV_KEY_RETIRES_AT (RFC 3339) if set, otherwise
+// JWT_ENV_KEY_GRACE_HOURS (default 24, at least the longest access token
+// lifetime) after the first stored key took over signing. Zero means never.
+func envKeyRetirement(stored []*jwtpkg.SigningKey, now time.Time) (time.Time, error) {
+	if v := os.Getenv("JWT_ENV_KEY_RETIRES_AT"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("JWT_ENV_KEY_RETIRES_AT: %w", err)
+		}
+		return t.UTC(), nil
+	}
+
+	var takeover time.Time
+	for _, k := range stored {
+		if k.IsActive(now) && (takeover.IsZero() || k.ActivatesAt.Before(takeover)) {
+			takeover = k.ActivatesAt
+		}
+	}
+	if takeover.IsZero() {
+		return time.Time{}, nil
+	}
+	grace := time.Duration(config.EnvInt("JWT_ENV_KEY_GRACE_HOURS", 24)) * time.Hour
+	return takeover.Add(grace), nil
+}
+
+// StartAutoReload periodically reloads the key ring so every replica picks up
+// rotations made elsewhere
+func StartAutoReload(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := Reload(ctx); err != nil {
+				log.Printf("⚠️  Failed to reload signing keys: %v", err)
+			}
+			cancel()
+		}
+	}()
+}
+
+func status(k KeyInfo, now time.Time) string {
+	switch {
+	case k.RetiresAt != nil && !now.Before(*k.RetiresAt):
+		return "retired"
+	case now.Before(k.ActivatesAt):
+		return "pending"
+	default:
+		return "active"
+	}
+}
+
+// seal encrypts a private key with AES-256-GCM keyed by JWT_KEYSTORE_SECRET
+func seal(plaintext []byte) (string, error) {
+	secret, err := keystoreSecret()
+	if err != nil {
+		return "", err
+	}
+	return utils.Seal(secret, plaintext)
+}
+
+func open(sealed string) ([]byte, error) {
+	secret, err := keystoreSecret()
+	if err != nil {
+		return nil, err
+	}
+	return utils.Open(secret, sealed)
+}
+
+func keystoreSecret() (string, error) {
+	secret := os.Getenv("JWT_KEYSTORE_SECRET")
+	if secret == "" {
+		return "", errors.New("JWT_KEYSTORE_SECRET is not set")
+	}
+	return secret, nil
+}
@@ -0,0 +1,47 @@
+package keystore
+
+import (
+	"testing"
+	"time"
+
+	jwtpkg "auth-service/pkg/jwt"
+)
+
+func TestEnvKeyRetirement(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	active := &jwtpkg.SigningKey{ActivatesAt: now.Add(-2 * time.Hour)}
+	newer := &jwtpkg.SigningKey{ActivatesAt: now.Add(-time.Hour)}
+	pending := &jwtpkg.SigningKey{ActivatesAt: now.Add(time.Hour)}
+	retired := &jwtpkg.SigningKey{ActivatesAt: now.Add(-48 * time.Hour), RetiresAt: now.Add(-time.Hour)}
+
+	cases := []struct {
+		name    string
+		env     map[string]string
+		stored  []*jwtpkg.SigningKey
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "no stored keys", want: time.Time{}},
+		{name: "only pending keys", stored: []*jwtpkg.SigningKey{pending}, want: time.Time{}},
+		{name: "grace after the first active key", stored: []*jwtpkg.SigningKey{newer, active, pending, retired}, want: active.ActivatesAt.Add(24 * time.Hour)},
+		{name: "custom grace", env: map[string]string{"JWT_ENV_KEY_GRACE_HOURS": "2"}, stored: []*jwtpkg.SigningKey{active}, want: now},
+		{name: "explicit retirement", env: map[string]string{"JWT_ENV_KEY_RETIRES_AT": "2025-07-01T00:00:00+02:00"}, stored: []*jwtpkg.SigningKey{active}, want: time.Date(2025, 6, 30, 22, 0, 0, 0, time.UTC)},
+		{name: "invalid retirement", env: map[string]string{"JWT_ENV_KEY_RETIRES_AT": "soon"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("JWT_ENV_KEY_RETIRES_AT", "")
+			t.Setenv("JWT_ENV_KEY_GRACE_HOURS", "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			got, err := envKeyRetirement(tc.stored, now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v", err)
+			}
+			if !got.Equal(tc.want) {
+				t.Fatalf("retires at %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
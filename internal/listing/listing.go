@@ -0,0 +1,315 @@
+package listing
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Limits on page size
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// ErrInvalidCursor is returned for a cursor that is malformed, was issued
+// for a different sort order or carries a value of the wrong type
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Column is a sortable expression and the SQL type its cursor value is cast to.
+// Sort columns must be NOT NULL so keyset comparisons never see NULL.
+type Column struct {
+	Expr string
+	Type string // e.g. "bigint", "text", "timestamp"
+}
+
+// Filter turns a query parameter into a WHERE condition. arg binds a value
+// and returns its placeholder.
+type Filter func(value string, arg func(any) string) (string, error)
+
+// Spec describes a listable resource
+type Spec struct {
+	Select      string            // column list, in the order the scan function expects
+	From        string            // FROM clause with aliases
+	Key         string            // unique integer column, the tiebreaker for every sort
+	Sorts       map[string]Column // sortable fields by public name
+	DefaultSort string            // e.g. "-created_at"; "-" sorts descending
+	Filters     map[string]Filter // by query parameter
+}
+
+// Query is a parsed page request
+type Query struct {
+	spec   Spec
+	sort   string
+	column Column
+	desc   bool
+	limit  int
+	where  []string
+	args   []any
+	after  *cursor
+}
+
+// Page is one page of results
+type Page[T any] struct {
+	Items      []T
+	Total      int64
+	NextCursor string
+	Limit      int
+}
+
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	Key   int64  `json:"k"`
+}
+
+// Parse reads limit, sort, cursor and the spec's filters from query
+// parameters. Unknown parameters are ignored; bad values are errors meant
+// for the client.
+func Parse(spec Spec, params map[string]string) (*Query, error) {
+	q := &Query{spec: spec, limit: DefaultLimit}
+
+	if v := params["limit"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, errors.New("limit must be a positive integer")
+		}
+		q.limit = min(n, MaxLimit)
+	}
+
+	q.sort = params["sort"]
+	if q.sort == "" {
+		q.sort = spec.DefaultSort
+	}
+	name := strings.TrimPrefix(q.sort, "-")
+	column, ok := spec.Sorts[name]
+	if !ok {
+		return nil, fmt.Errorf("cannot sort by %q", name)
+	}
+	q.column, q.desc = column, strings.HasPrefix(q.sort, "-")
+
+	for param, filter := range spec.Filters {
+		value, ok := params[param]
+		if !ok || value == "" {
+			continue
+		}
+		cond, err := filter(value, q.arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", param, err)
+		}
+		q.where = append(q.where, cond)
+	}
+
+	if v := params["cursor"]; v != "" {
+		c, err := decodeCursor(v)
+		if err != nil || c.Sort != q.sort || !validCursorValue(column.Type, c.Value) {
+			return nil, ErrInvalidCursor
+		}
+		q.after = c
+	}
+	return q, nil
+}
+
+// arg binds a value and returns its placeholder
+func (q *Query) arg(v any) string {
+	q.args = append(q.args, v)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+// Fetch runs the query. scan reads one row by passing its destinations to
+// the given function, in Spec.Select order.
+func Fetch[T any](ctx context.Context, db Querier, q *Query, scan func(scan func(dest ...any) error) (T, error)) (*Page[T], error) {
+	page := &Page[T]{Items: []T{}, Limit: q.limit}
+
+	// The total ignores the cursor: it counts every match of the filters
+	where := q.whereClause(nil)
+	err := db.QueryRow(ctx, "SELECT count(*) FROM "+q.spec.From+where+";", q.args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]any{}, q.args...)
+	var keyset []string
+	if q.after != nil {
+		op := ">"
+		if q.desc {
+			op = "<"
+		}
+		args = append(args, q.after.Value, q.after.Key)
+		keyset = append(keyset, fmt.Sprintf("(%s, %s) %s ($%d::%s, $%d::bigint)",
+			q.column.Expr, q.spec.Key, op, len(args)-1, q.column.Type, len(args)))
+	}
+
+	dir := "ASC"
+	if q.desc {
+		dir = "DESC"
+	}
+	// One extra row tells whether there is a next page
+	sql := fmt.Sprintf("SELECT %s, (%s)::text, %s FROM %s%s ORDER BY %s %s, %s %s LIMIT %d;",
+		q.spec.Select, q.column.Expr, q.spec.Key, q.spec.From, q.whereClause(keyset),
+		q.column.Expr, dir, q.spec.Key, dir, q.limit+1)
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var last cursor
+	for rows.Next() {
+		if len(page.Items) == q.limit {
+			page.NextCursor = encodeCursor(cursor{Sort: q.sort, Value: last.Value, Key: last.Key})
+			break
+		}
+		item, err := scan(func(dest ...any) error {
+			return rows.Scan(append(dest, &last.Value, &last.Key)...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Querier is satisfied by *pgxpool.Pool and pgx.Tx
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func (q *Query) whereClause(extra []string) string {
+	conds := append(append([]string{}, q.where...), extra...)
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// timestampLayouts are the text forms Postgres gives a timestamp (and a
+// timestamptz, which adds the zone offset)
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999-07:00",
+}
+
+// validCursorValue reports whether a cursor value casts to the sort column's
+// type, so a tampered cursor is a client error rather than a failed query
+func validCursorValue(typ, value string) bool {
+	switch typ {
+	case "bigint":
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case "integer":
+		_, err := strconv.ParseInt(value, 10, 32)
+		return err == nil
+	case "timestamp", "timestamptz":
+		for _, layout := range timestampLayouts {
+			if _, err := time.Parse(layout, value); err == nil {
+				return true
+			}
+		}
+		return false
+	default:
+		// Postgres text cannot hold NUL, which JSON can encode
+		return !strings.ContainsRune(value, 0)
+	}
+}
+
+// Common filters
+
+// Equals matches column against the value converted by parse
+func Equals(column string, parse func(string) (any, error)) Filter {
+	return func(value string, arg func(any) string) (string, error) {
+		v, err := parse(value)
+		if err != nil {
+			return "", err
+		}
+		return column + " = " + arg(v), nil
+	}
+}
+
+// Bool parses "true" / "false"
+func Bool(s string) (any, error) {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, errors.New("must be true or false")
+	}
+	return b, nil
+}
+
+// Int parses an integer
+func Int(s string) (any, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, errors.New("must be an integer")
+	}
+	return n, nil
+}
+
+// Text accepts any value
+func Text(s string) (any, error) { return s, nil }
+
+// TimeRange matches column >= value (after) or column < value (before).
+// Values are RFC 3339 timestamps or dates (2006-01-02).
+func TimeRange(column string, after bool) Filter {
+	return func(value string, arg func(any) string) (string, error) {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if t, err = time.Parse(time.DateOnly, value); err != nil {
+				return "", errors.New("must be an RFC 3339 timestamp or a date (YYYY-MM-DD)")
+			}
+		}
+		if after {
+			return column + " >= " + arg(t.UTC()), nil
+		}
+		return column + " < " + arg(t.UTC()), nil
+	}
+}
+
+// Prefix matches lower(column) starting with the value; backed by a
+// lower(column) text_pattern_ops index
+func Prefix(column string) Filter {
+	return func(value string, arg func(any) string) (string, error) {
+		return "lower(" + column + ") LIKE " + arg(escapeLike(strings.ToLower(value))+"%"), nil
+	}
+}
+
+// Contains matches lower(column) containing the value; backed by a pg_trgm index
+func Contains(column string) Filter {
+	return func(value string, arg func(any) string) (string, error) {
+		return "lower(" + column + ") LIKE " + arg("%"+escapeLike(strings.ToLower(value))+"%"), nil
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,132 @@
+package listing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var spec = Spec{
+	Select: "u.id",
+	From:   "users u",
+	Key:    "u.id",
+	Sorts: map[string]Column{
+		"id":         {Expr: "u.id", Type: "bigint"},
+		"created_at": {Expr: "u.created_at", Type: "timestamp"},
+	},
+	DefaultSort: "id",
+	Filters: map[string]Filter{
+		"is_active":    Equals("u.is_active", Bool),
+		"email_prefix": Prefix("u.email"),
+		"since":        TimeRange("u.created_at", true),
+	},
+}
+
+func TestParseDefaultsAndLimits(t *testing.T) {
+	q, err := Parse(spec, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.limit != DefaultLimit || q.sort != "id" || q.desc {
+		t.Fatalf("defaults = limit %d sort %q desc %v", q.limit, q.sort, q.desc)
+	}
+
+	q, err = Parse(spec, map[string]string{"limit": "100000", "sort": "-created_at"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.limit != MaxLimit || !q.desc || q.column.Expr != "u.created_at" {
+		t.Fatalf("got limit %d desc %v column %q", q.limit, q.desc, q.column.Expr)
+	}
+
+	for _, params := range []map[string]string{
+		{"limit": "0"},
+		{"limit": "ten"},
+		{"sort": "password_hash"},
+		{"is_active": "maybe"},
+		{"since": "yesterday"},
+	} {
+		if _, err := Parse(spec, params); err == nil {
+			t.Errorf("Parse(%v) succeeded, want an error", params)
+		}
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	q, err := Parse(spec, map[string]string{"is_active": "true", "email_prefix": "Ann_%", "since": "2025-01-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	where := q.whereClause(nil)
+	for _, want := range []string{"u.is_active = $", "lower(u.email) LIKE $", "u.created_at >= $"} {
+		if !strings.Contains(where, want) {
+			t.Errorf("where clause %q lacks %q", where, want)
+		}
+	}
+	if len(q.args) != 3 {
+		t.Fatalf("args = %v", q.args)
+	}
+	for _, a := range q.args {
+		if s, ok := a.(string); ok && s != `ann\_\%%` {
+			t.Errorf("prefix pattern = %q, want LIKE wildcards escaped", s)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	c := encodeCursor(cursor{Sort: "-created_at", Value: "2025-01-02 03:04:05.123456", Key: 42})
+
+	q, err := Parse(spec, map[string]string{"sort": "-created_at", "cursor": c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.after == nil || q.after.Key != 42 || q.after.Value != "2025-01-02 03:04:05.123456" {
+		t.Fatalf("after = %+v", q.after)
+	}
+
+	// A cursor only continues the sort order it was issued for
+	if _, err := Parse(spec, map[string]string{"sort": "created_at", "cursor": c}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("mismatched sort: err = %v", err)
+	}
+	if _, err := Parse(spec, map[string]string{"cursor": "not-a-cursor"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("garbage cursor: err = %v", err)
+	}
+}
+
+func TestCursorValueType(t *testing.T) {
+	spec := spec
+	spec.Sorts = map[string]Column{
+		"id":         {Expr: "u.id", Type: "bigint"},
+		"created_at": {Expr: "u.created_at", Type: "timestamp"},
+		"email":      {Expr: "u.email", Type: "text"},
+	}
+	for _, tc := range []struct {
+		sort, value string
+		ok          bool
+	}{
+		{"id", "42", true},
+		{"id", "-7", true},
+		{"id", "4x", false},
+		{"id", "", false},
+		{"id", "99999999999999999999", false},
+		{"created_at", "2025-01-02 03:04:05", true},
+		{"created_at", "2025-01-02 03:04:05.123456", true},
+		{"created_at", "2025-01-02 03:04:05.123456+00", true},
+		{"created_at", "2025-01-02 03:04:05+05:30", true},
+		{"created_at", "yesterday", false},
+		{"created_at", "2025-13-02 03:04:05", false},
+		{"created_at", "42", false},
+		{"email", "a@example.com", true},
+		{"email", "", true},
+		{"email", "a\x00b", false},
+	} {
+		c := encodeCursor(cursor{Sort: tc.sort, Value: tc.value, Key: 1})
+		_, err := Parse(spec, map[string]string{"sort": tc.sort, "cursor": c})
+		if tc.ok && err != nil {
+			t.Errorf("%s cursor %q: err = %v", tc.sort, tc.value, err)
+		}
+		if !tc.ok && !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s cursor %q: err = %v, want ErrInvalidCursor", tc.sort, tc.value, err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package lockout
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"auth-service/internal/db"
+	"auth-service/internal/policies"
+)
+
+// Settings are the brute-force thresholds, read from auth_policies
+type Settings struct {
+	MaxFailedLogins      int           // per account, before lockout
+	MaxFailedLoginsPerIP int           // per source IP, before lockout
+	LockoutDuration      time.Duration // also the window after which counters reset
+	BackoffThreshold     int           // failures before progressive delays start
+	BackoffBase          time.Duration // first delay, doubled on every further failure
+}
+
+// Status tells the login handler whether an attempt may proceed
+type Status struct {
+	Locked     bool // hard lockout: too many failures
+	Throttled  bool // progressive delay still running
+	RetryAfter time.Duration
+}
+
+// LoadSettings reads the current thresholds; it fails rather than fall back
+// to the defaults when the policies cannot be read
+func LoadSettings(ctx context.Context) (Settings, error) {
+	r := policies.NewReader(ctx)
+	s := Settings{
+		MaxFailedLogins:      r.Int("max_failed_logins", 5),
+		MaxFailedLoginsPerIP: r.Int("max_failed_logins_per_ip", 50),
+		LockoutDuration:      r.Duration("lockout_duration", 15*time.Minute),
+		BackoffThreshold:     r.Int("login_backoff_threshold", 3),
+		BackoffBase:          r.Duration("login_backoff_base", time.Second),
+	}
+	return s, r.Err
+}
+
+// Keys are tracked for any email, existing or not, so a lockout never
+// reveals whether an account exists
+func emailKey(email string) string { return "email:" + strings.ToLower(strings.TrimSpace(email)) }
+func ipKey(ip string) string       { return "ip:" + ip }
+
+// Check reports whether a login for email from ip is currently locked or throttled
+func Check(ctx context.Context, email, ip string) (Status, error) {
+	s, err := LoadSettings(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+
+	rows, err := db.DB.Query(ctx, `
+		SELECT key, failures, last_failure_at, locked_until, NOW()::timestamp
+		FROM login_failures
+		WHERE key = ANY($1);
+	`, []string{emailKey(email), ipKey(ip)})
+	if err != nil {
+		return Status{}, err
+	}
+	defer rows.Close()
+
+	var status Status
+	for rows.Next() {
+		var key string
+		var failures int
+		var lastFailure, now time.Time
+		var lockedUntil *time.Time
+		if err := rows.Scan(&key, &failures, &lastFailure, &lockedUntil, &now); err != nil {
+			return Status{}, err
+		}
+
+		if lockedUntil != nil && now.Before(*lockedUntil) {
+			status.Locked = true
+			status.RetryAfter = max(status.RetryAfter, lockedUntil.Sub(now))
+			continue
+		}
+
+		// Progressive delay only applies to accounts, not shared IPs
+		if strings.HasPrefix(key, "email:") && failures >= s.BackoffThreshold && now.Sub(lastFailure) < s.LockoutDuration {
+			delay := backoff(s, failures)
+			if wait := lastFailure.Add(delay).Sub(now); wait > 0 {
+				status.Throttled = true
+				status.RetryAfter = max(status.RetryAfter, wait)
+			}
+		}
+	}
+	return status, rows.Err()
+}
+
+// RecordFailure counts a failed attempt against both the email and the IP and
+// starts a lockout when either crosses its threshold
+func RecordFailure(ctx context.Context, email, ip string) error {
+	s, err := LoadSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range []struct {
+		key   string
+		limit int
+	}{
+		{emailKey(email), s.MaxFailedLogins},
+		{ipKey(ip), s.MaxFailedLoginsPerIP},
+	} {
+		// Counters older than the lockout window start over
+		var failures int
+		err := db.DB.QueryRow(ctx, `
+			INSERT INTO login_failures (key, failures, last_failure_at)
+			VALUES ($1, 1, NOW())
+			ON CONFLICT (key) DO UPDATE SET
+				failures = CASE
+					WHEN login_failures.last_failure_at < NOW() - make_interval(secs => $2) THEN 1
+					ELSE login_failures.failures + 1
+				END,
+				last_failure_at = NOW()
+			RETURNING failures;
+		`, k.key, s.LockoutDuration.Seconds()).Scan(&failures)
+		if err != nil {
+			return err
+		}
+
+		if k.limit > 0 && failures >= k.limit {
+			_, err = db.DB.Exec(ctx, `
+				UPDATE login_failures SET locked_until = NOW() + make_interval(secs => $2)
+				WHERE key = $1;
+			`, k.key, s.LockoutDuration.Seconds())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RecordSuccess clears the account's failure counter. The IP counter is kept
+// so one valid account cannot be used to reset an IP spraying other accounts.
+func RecordSuccess(ctx context.Context, email string) error {
+	_, err := db.DB.Exec(ctx, "DELETE FROM login_failures WHERE key = $1;", emailKey(email))
+	return err
+}
+
+// Unlock lifts a lockout and resets the failure counter for an account
+func Unlock(ctx context.Context, email string) error {
+	return RecordSuccess(ctx, email)
+}
+
+// UnlockIP lifts a lockout for a source IP
+func UnlockIP(ctx context.Context, ip string) error {
+	_, err := db.DB.Exec(ctx, "DELETE FROM login_failures WHERE key = $1;", ipKey(ip))
+	return err
+}
+
+func backoff(s Settings, failures int) time.Duration {
+	steps := failures - s.BackoffThreshold
+	delay := time.Duration(float64(s.BackoffBase) * math.Pow(2, float64(steps)))
+	if delay > s.LockoutDuration || delay <= 0 {
+		return s.LockoutDuration
+	}
+	return delay
+}
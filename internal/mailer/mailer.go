@@ -0,0 +1,117 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"auth-service/internal/config"
+)
+
+// Message is one outbound email
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes messages to the log instead of sending them (development)
+type LogMailer struct{}
+
+// Send implements Mailer
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	log.Printf("📧 Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+// FileMailer writes each message as an .eml file into Dir (development)
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// Send implements Mailer
+func (m FileMailer) Send(_ context.Context, msg Message) error {
+	data, err := buildMIME(m.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFileName(msg.To))
+	return os.WriteFile(filepath.Join(m.Dir, name), data, 0o600)
+}
+
+var (
+	mu      sync.RWMutex
+	current Mailer = LogMailer{}
+)
+
+// SetMailer replaces the mailer used by Send and the outbox worker
+func SetMailer(m Mailer) {
+	mu.Lock()
+	current = m
+	mu.Unlock()
+}
+
+// Send delivers msg immediately through the configured mailer. Most callers
+// want Queue instead, which survives an SMTP outage.
+func Send(ctx context.Context, msg Message) error {
+	mu.RLock()
+	m := current
+	mu.RUnlock()
+	return m.Send(ctx, msg)
+}
+
+// FromEnv builds the mailer selected by MAIL_DRIVER (log, file or smtp)
+func FromEnv() (Mailer, error) {
+	from := config.Env("MAIL_FROM", "auth-service <no-reply@localhost>")
+
+	switch driver := config.Env("MAIL_DRIVER", "log"); driver {
+	case "log":
+		return LogMailer{}, nil
+	case "file":
+		return FileMailer{Dir: config.Env("MAIL_FILE_DIR", "mail"), From: from}, nil
+	case "smtp":
+		port, err := strconv.Atoi(config.Env("SMTP_PORT", "587"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+		}
+		tlsMode, err := ParseTLSMode(config.Env("SMTP_TLS", string(TLSStartTLS)))
+		if err != nil {
+			return nil, err
+		}
+		return &SMTPMailer{
+			Host:     config.Env("SMTP_HOST", "localhost"),
+			Port:     port,
+			Username: config.Env("SMTP_USERNAME", ""),
+			Password: config.Env("SMTP_PASSWORD", ""),
+			From:     from,
+			TLS:      tlsMode,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", driver)
+	}
+}
+
+func sanitizeFileName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '@') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
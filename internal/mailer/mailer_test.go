@@ -0,0 +1,234 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpStandIn is a minimal local SMTP server that records what it receives
+type smtpStandIn struct {
+	ln       net.Listener
+	received chan received
+	reject   bool // answer DATA with a temporary failure
+}
+
+type received struct {
+	from, to string
+	data     string
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStandIn{ln: ln, received: make(chan received, 10)}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpStandIn) port() int { return s.ln.Addr().(*net.TCPAddr).Port }
+
+func (s *smtpStandIn) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStandIn) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	var msg received
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg.from = strings.Trim(strings.TrimSpace(line)[10:], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.to = strings.Trim(strings.TrimSpace(line)[8:], "<>")
+			reply("250 OK")
+		case cmd == "DATA":
+			if s.reject {
+				reply("451 try again later")
+				continue
+			}
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			msg.data = data.String()
+			s.received <- msg
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailerSendsMultipartMessage(t *testing.T) {
+	srv := newSMTPStandIn(t)
+	m := &SMTPMailer{Host: "127.0.0.1", Port: srv.port(), From: "Auth <no-reply@example.com>", TLS: TLSNone}
+
+	msg, err := Render("verify_email", "alice@example.com", map[string]string{
+		"Link":      "https://auth.example.com/verify?token=abc",
+		"ExpiresIn": "24 hours",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	var got received
+	select {
+	case got = <-srv.received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stand-in received nothing")
+	}
+	if got.from != "no-reply@example.com" || got.to != "alice@example.com" {
+		t.Fatalf("envelope %s -> %s", got.from, got.to)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(got.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject")); s != "Verify your email address" {
+		t.Fatalf("subject %q", s)
+	}
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := map[string]string{}
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(p)
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts[ct] = string(body)
+	}
+	for _, ct := range []string{"text/plain", "text/html"} {
+		if !strings.Contains(parts[ct], "https://auth.example.com/verify?token=abc") {
+			t.Fatalf("%s part missing link: %q", ct, parts[ct])
+		}
+	}
+}
+
+func TestSMTPMailerReportsServerErrors(t *testing.T) {
+	srv := newSMTPStandIn(t)
+	srv.reject = true
+	m := &SMTPMailer{Host: "127.0.0.1", Port: srv.port(), From: "no-reply@example.com", TLS: TLSNone}
+
+	if err := m.Send(context.Background(), Message{To: "bob@example.com", Subject: "x", Text: "y"}); err == nil {
+		t.Fatal("expected an error for a 451 reply")
+	}
+}
+
+func TestTemplatesCanBeOverriddenFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "password_reset.subject.tmpl"), []byte("Custom reset for {{.Link}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MAIL_TEMPLATES_DIR", dir)
+
+	msg, err := Render("password_reset", "alice@example.com", map[string]string{"Link": "L", "ExpiresIn": "1 hour"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Custom reset for L" {
+		t.Fatalf("subject %q, want override", msg.Subject)
+	}
+	// Files that are not overridden fall back to the built-in templates
+	if !strings.Contains(msg.Text, "expires in 1 hour") || msg.HTML == "" {
+		t.Fatalf("built-in bodies not used: %q", msg.Text)
+	}
+}
+
+func TestHTMLTemplatesEscapeData(t *testing.T) {
+	msg, err := Render("verify_email", "a@example.com", map[string]string{"Link": `"><script>x</script>`, "ExpiresIn": "1 hour"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(msg.HTML, "<script>") {
+		t.Fatal("HTML template did not escape data")
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		time.Hour:        "1 hour",
+		24 * time.Hour:   "24 hours",
+		72 * time.Hour:   "3 days",
+		30 * time.Minute: "30 minutes",
+		time.Minute:      "1 minute",
+	} {
+		if got := HumanDuration(d); got != want {
+			t.Errorf("HumanDuration(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestParseTLSMode(t *testing.T) {
+	for in, want := range map[string]TLSMode{
+		"starttls":   TLSStartTLS,
+		" STARTTLS ": TLSStartTLS,
+		"tls":        TLSImplicit,
+		"None":       TLSNone,
+	} {
+		got, err := ParseTLSMode(in)
+		if err != nil || got != want {
+			t.Errorf("ParseTLSMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "ssl", "true", "start_tls"} {
+		if _, err := ParseTLSMode(in); err == nil {
+			t.Errorf("ParseTLSMode(%q) accepted an unknown mode", in)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/db"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Execer is satisfied by the pool and by a transaction, so a mail can be
+// queued atomically with the change that triggers it
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+const (
+	outboxBatchSize = 20
+	outboxLease     = 5 * time.Minute // a crashed worker's claim expires after this
+	retryBase       = 30 * time.Second
+	retryMax        = time.Hour
+)
+
+// wake lets Enqueue nudge the worker instead of waiting for the next tick
+var wake = make(chan struct{}, 1)
+
+// Enqueue stores msg in email_outbox; the worker delivers it with retries
+func Enqueue(ctx context.Context, q Execer, msg Message) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO email_outbox (recipient, subject, text_body, html_body, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW());
+	`, msg.To, msg.Subject, msg.Text, msg.HTML, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Queue renders the named template and enqueues the result
+func Queue(ctx context.Context, q Execer, template, to string, data any) error {
+	msg, err := Render(template, to, data)
+	if err != nil {
+		return err
+	}
+	return Enqueue(ctx, q, msg)
+}
+
+// StartWorker delivers queued mail every interval (and right after Enqueue).
+// Several replicas can run it; rows are claimed with SKIP LOCKED.
+func StartWorker(interval time.Duration) {
+	maxAttempts := config.EnvInt("MAIL_MAX_ATTEMPTS", 10)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			for {
+				n, err := deliverBatch(context.Background(), maxAttempts)
+				if err != nil {
+					log.Printf("⚠️  Email outbox: %v", err)
+				}
+				if n < outboxBatchSize {
+					break
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-wake:
+			}
+		}
+	}()
+}
+
+type outboxEntry struct {
+	id       int64
+	attempts int
+	msg      Message
+}
+
+// deliverBatch claims due messages, sends them and records the outcome.
+// Bodies carry single-use links (password reset, email verification), so
+// they are cleared once a message is sent or given up on.
+func deliverBatch(ctx context.Context, maxAttempts int) (int, error) {
+	now := time.Now().UTC()
+	rows, err := db.DB.Query(ctx, `
+		UPDATE email_outbox SET locked_until = $2, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			  AND (locked_until IS NULL OR locked_until < $1)
+			ORDER BY id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, attempts, recipient, subject, text_body, COALESCE(html_body, '');
+	`, now, now.Add(outboxLease), outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var batch []outboxEntry
+	for rows.Next() {
+		var e outboxEntry
+		if err := rows.Scan(&e.id, &e.attempts, &e.msg.To, &e.msg.Subject, &e.msg.Text, &e.msg.HTML); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, e := range batch {
+		if sendErr := Send(ctx, e.msg); sendErr != nil {
+			err = markFailed(ctx, e, maxAttempts, sendErr)
+		} else {
+			_, err = db.DB.Exec(ctx, `
+				UPDATE email_outbox
+				SET status = 'sent', sent_at = NOW(), locked_until = NULL, last_error = NULL,
+				    text_body = '', html_body = NULL
+				WHERE id = $1;
+			`, e.id)
+		}
+		if err != nil {
+			return len(batch), err
+		}
+	}
+	return len(batch), nil
+}
+
+// markFailed schedules a retry with exponential backoff, or gives up
+func markFailed(ctx context.Context, e outboxEntry, maxAttempts int, sendErr error) error {
+	if e.attempts >= maxAttempts {
+		log.Printf("❌ Giving up on email %d to %s after %d attempts: %v", e.id, e.msg.To, e.attempts, sendErr)
+		_, err := db.DB.Exec(ctx, `
+			UPDATE email_outbox
+			SET status = 'failed', locked_until = NULL, last_error = $2, text_body = '', html_body = NULL
+			WHERE id = $1;
+		`, e.id, sendErr.Error())
+		return err
+	}
+
+	delay := retryBase << (e.attempts - 1)
+	if delay > retryMax || delay <= 0 {
+		delay = retryMax
+	}
+	log.Printf("⚠️  Email %d to %s failed (attempt %d), retrying in %s: %v", e.id, e.msg.To, e.attempts, delay, sendErr)
+	_, err := db.DB.Exec(ctx, `
+		UPDATE email_outbox SET next_attempt_at = $2, locked_until = NULL, last_error = $3
+		WHERE id = $1;
+	`, e.id, time.Now().UTC().Add(delay), sendErr.Error())
+	return err
+}
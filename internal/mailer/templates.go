@@ -0,0 +1,101 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"auth-service/internal/config"
+)
+
+// Built-in templates. Each mail has <name>.subject.tmpl, <name>.txt.tmpl and
+// an optional <name>.html.tmpl; a file with the same name in
+// MAIL_TEMPLATES_DIR replaces the built-in one.
+//
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// Render builds a message from the named templates
+func Render(name, to string, data any) (Message, error) {
+	subject, err := renderText(name+".subject.tmpl", data)
+	if err != nil {
+		return Message{}, err
+	}
+	text, err := renderText(name+".txt.tmpl", data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{To: to, Subject: strings.TrimSpace(subject), Text: text}
+
+	src, err := loadTemplate(name + ".html.tmpl")
+	if err == nil {
+		t, err := htmltemplate.New(name).Parse(src)
+		if err != nil {
+			return Message{}, err
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return Message{}, err
+		}
+		msg.HTML = buf.String()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
+func renderText(file string, data any) (string, error) {
+	src, err := loadTemplate(file)
+	if err != nil {
+		return "", err
+	}
+	t, err := texttemplate.New(file).Parse(src)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// loadTemplate prefers an override on disk over the built-in template
+func loadTemplate(file string) (string, error) {
+	if dir := config.Env("MAIL_TEMPLATES_DIR", ""); dir != "" {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err == nil {
+			return string(data), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	data, err := fs.ReadFile(builtinTemplates, "templates/"+file)
+	return string(data), err
+}
+
+// HumanDuration formats a link lifetime for templates ("24 hours", "30 minutes")
+func HumanDuration(d time.Duration) string {
+	unit, n := "minute", int(d.Round(time.Minute)/time.Minute)
+	switch {
+	case d >= 48*time.Hour && d%(24*time.Hour) == 0:
+		unit, n = "day", int(d/(24*time.Hour))
+	case d >= time.Hour && d%time.Hour == 0:
+		unit, n = "hour", int(d/time.Hour)
+	}
+	if n == 1 {
+		return "1 " + unit
+	}
+	return strconv.Itoa(n) + " " + unit + "s"
+}
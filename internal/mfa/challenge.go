@@ -0,0 +1,87 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"auth-service/internal/db"
+	"auth-service/internal/policies"
+	"auth-service/internal/utils"
+)
+
+// Challenge purposes: a second factor is due, or the user must enroll first
+// because a role forces MFA
+const (
+	PurposeVerify = "verify"
+	PurposeSetup  = "setup"
+)
+
+// maxChallengeAttempts bounds code guesses against a single challenge
+const maxChallengeAttempts = 5
+
+var ErrChallengeInvalid = errors.New("invalid or expired MFA token")
+
+// Challenge is a pending login that has passed the password check
+type Challenge struct {
+	ID       int
+	UserID   int
+	Purpose  string
+	Audience string
+}
+
+// NewChallenge starts a short-lived, single-use MFA challenge and returns its
+// opaque token. Only the hash is stored.
+func NewChallenge(ctx context.Context, userID int, purpose, audience string) (string, time.Duration, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", 0, err
+	}
+
+	ttl := policies.Duration(ctx, "mfa_challenge_ttl", 5*time.Minute)
+	_, err = db.DB.Exec(ctx, `
+		INSERT INTO mfa_challenges (user_id, token_hash, purpose, audience, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW());
+	`, userID, utils.HashToken(token), purpose, audience, time.Now().UTC().Add(ttl))
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Expired challenges are useless; prune them opportunistically
+	_, _ = db.DB.Exec(ctx, "DELETE FROM mfa_challenges WHERE expires_at < $1;", time.Now().UTC())
+
+	return token, ttl, nil
+}
+
+// LoadChallenge returns the live challenge for token if it has the given purpose
+func LoadChallenge(ctx context.Context, token, purpose string) (*Challenge, error) {
+	var ch Challenge
+	err := db.DB.QueryRow(ctx, `
+		SELECT id, user_id, purpose, audience
+		FROM mfa_challenges
+		WHERE token_hash = $1 AND purpose = $2 AND expires_at > $3 AND attempts < $4;
+	`, utils.HashToken(token), purpose, time.Now().UTC(), maxChallengeAttempts).
+		Scan(&ch.ID, &ch.UserID, &ch.Purpose, &ch.Audience)
+	if err != nil {
+		return nil, ErrChallengeInvalid
+	}
+	return &ch, nil
+}
+
+// Fail records a wrong code; the challenge dies after maxChallengeAttempts
+func (ch *Challenge) Fail(ctx context.Context) error {
+	_, err := db.DB.Exec(ctx, "UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id = $1;", ch.ID)
+	return err
+}
+
+// Consume deletes the challenge; it fails if another request already used it
+func (ch *Challenge) Consume(ctx context.Context) error {
+	tag, err := db.DB.Exec(ctx, "DELETE FROM mfa_challenges WHERE id = $1;", ch.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrChallengeInvalid
+	}
+	return nil
+}
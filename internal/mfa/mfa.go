@@ -0,0 +1,297 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"auth-service/internal/db"
+	"auth-service/internal/policies"
+	"auth-service/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrAlreadyEnabled = errors.New("MFA is already enabled")
+	ErrNotEnrolled    = errors.New("MFA enrollment has not been started")
+	ErrInvalidCode    = errors.New("invalid code")
+)
+
+// recoveryCodeCount is how many single-use recovery codes a user holds
+const recoveryCodeCount = 10
+
+// Status describes a user's MFA setup
+type Status struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// Required reports whether the mfa_required_roles policy forces MFA on any
+// of roles. An unreadable policy is an error, never "not required".
+func Required(ctx context.Context, roles []string) (bool, error) {
+	r := policies.NewReader(ctx)
+	required := r.StringSlice("mfa_required_roles", nil)
+	if r.Err != nil {
+		return false, r.Err
+	}
+	for _, role := range roles {
+		if slices.Contains(required, role) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Enabled reports whether the user has confirmed a TOTP enrollment
+func Enabled(ctx context.Context, userID int) (bool, error) {
+	var enabled bool
+	err := db.DB.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM user_mfa WHERE user_id = $1 AND enabled_at IS NOT NULL);
+	`, userID).Scan(&enabled)
+	return enabled, err
+}
+
+// GetStatus returns the user's MFA status
+func GetStatus(ctx context.Context, userID int) (Status, error) {
+	var s Status
+	err := db.DB.QueryRow(ctx, `
+		SELECT
+			(SELECT enabled_at FROM user_mfa WHERE user_id = $1),
+			(SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL);
+	`, userID).Scan(&s.EnabledAt, &s.RecoveryCodesRemaining)
+	s.Enabled = s.EnabledAt != nil
+	return s, err
+}
+
+// StartEnrollment stores a fresh, unconfirmed secret (replacing any earlier
+// unconfirmed one) and returns it with its otpauth URI
+func StartEnrollment(ctx context.Context, userID int, account string) (secret, uri string, err error) {
+	if enabled, err := Enabled(ctx, userID); err != nil {
+		return "", "", err
+	} else if enabled {
+		return "", "", ErrAlreadyEnabled
+	}
+
+	secret, err = GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err := sealSecret(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = db.DB.Exec(ctx, `
+		INSERT INTO user_mfa (user_id, secret, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = NULL, created_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL;
+	`, userID, sealed)
+	if err != nil {
+		return "", "", err
+	}
+
+	issuer := os.Getenv("MFA_ISSUER")
+	if issuer == "" {
+		issuer = "auth-service"
+	}
+	return secret, OTPAuthURI(issuer, account, secret), nil
+}
+
+// ConfirmEnrollment enables MFA once the user proves the authenticator works
+// and returns the raw recovery codes (shown once, stored hashed)
+func ConfirmEnrollment(ctx context.Context, userID int, code string) ([]string, error) {
+	tx, err := db.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var sealed string
+	var enabledAt *time.Time
+	err = tx.QueryRow(ctx, "SELECT secret, enabled_at FROM user_mfa WHERE user_id = $1 FOR UPDATE;", userID).
+		Scan(&sealed, &enabledAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotEnrolled
+	} else if err != nil {
+		return nil, err
+	}
+	if enabledAt != nil {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := openSecret(sealed)
+	if err != nil {
+		return nil, err
+	}
+	usedStep, ok := ValidateCode(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE user_mfa SET enabled_at = NOW(), last_used_step = $2 WHERE user_id = $1;", userID, usedStep)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit(ctx)
+}
+
+// VerifyCode checks a TOTP code for an enabled user. A step is accepted only
+// once so an observed code cannot be replayed.
+func VerifyCode(ctx context.Context, userID int, code string) (bool, error) {
+	tx, err := db.DB.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var sealed string
+	var lastStep *int64
+	err = tx.QueryRow(ctx, `
+		SELECT secret, last_used_step FROM user_mfa
+		WHERE user_id = $1 AND enabled_at IS NOT NULL
+		FOR UPDATE;
+	`, userID).Scan(&sealed, &lastStep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	secret, err := openSecret(sealed)
+	if err != nil {
+		return false, err
+	}
+	usedStep, ok := validateUnused(secret, code, time.Now(), lastStep)
+	if !ok {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1;", userID, usedStep); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// UseRecoveryCode consumes one unused recovery code
+func UseRecoveryCode(ctx context.Context, userID int, code string) (bool, error) {
+	tag, err := db.DB.Exec(ctx, `
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+	`, userID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// RegenerateRecoveryCodes invalidates every existing recovery code and issues a new set
+func RegenerateRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	tx, err := db.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit(ctx)
+}
+
+// Disable removes the user's TOTP secret and recovery codes
+func Disable(ctx context.Context, userID int) error {
+	tx, err := db.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM user_mfa WHERE user_id = $1;", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1;", userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int) ([]string, error) {
+	if _, err := tx.Exec(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1;", userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at)
+			VALUES ($1, $2, NOW());
+		`, userID, utils.HashToken(normalizeRecoveryCode(code)))
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+	return codes, nil
+}
+
+// newRecoveryCode returns a code like "k7m2p-xq4ta" (50 bits of entropy)
+func newRecoveryCode() (string, error) {
+	const alphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b[:5]) + "-" + string(b[5:]), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// Secrets are encrypted with MFA_ENCRYPTION_KEY, falling back to JWT_KEYSTORE_SECRET
+func encryptionKey() (string, error) {
+	for _, name := range []string{"MFA_ENCRYPTION_KEY", "JWT_KEYSTORE_SECRET"} {
+		if key := os.Getenv(name); key != "" {
+			return key, nil
+		}
+	}
+	return "", errors.New("MFA_ENCRYPTION_KEY is not set")
+}
+
+func sealSecret(secret string) (string, error) {
+	key, err := encryptionKey()
+	if err != nil {
+		return "", err
+	}
+	return utils.Seal(key, []byte(secret))
+}
+
+func openSecret(sealed string) (string, error) {
+	key, err := encryptionKey()
+	if err != nil {
+		return "", err
+	}
+	secret, err := utils.Open(key, sealed)
+	return string(secret), err
+}
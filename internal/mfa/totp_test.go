@@ -0,0 +1,91 @@
+package mfa
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of RFC 6238 Appendix B, "12345678901234567890"
+var rfc6238Secret = b32.EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeRFC6238Vectors(t *testing.T) {
+	// Appendix B lists 8-digit codes; 6-digit codes are their last six digits
+	for unix, want := range map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	} {
+		got, err := Code(rfc6238Secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want[2:] {
+			t.Errorf("Code at %d = %s, want %s", unix, got, want[2:])
+		}
+	}
+
+	// Secrets are accepted in lower case, as some apps display them
+	if got, _ := Code(strings.ToLower(rfc6238Secret), time.Unix(59, 0)); got != "287082" {
+		t.Errorf("lower-case secret: Code = %s", got)
+	}
+	if _, err := Code("not base32!", time.Unix(59, 0)); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestValidateCodeSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0) // step 37037037
+	current := step(now)
+
+	for offset, ok := range map[int64]bool{-2: false, -1: true, 0: true, 1: true, 2: false} {
+		code, _ := Code(rfc6238Secret, now.Add(time.Duration(offset)*totpPeriod))
+		gotStep, gotOK := ValidateCode(rfc6238Secret, code, now)
+		if gotOK != ok {
+			t.Errorf("offset %d: ok = %v, want %v", offset, gotOK, ok)
+		}
+		if ok && gotStep != current+offset {
+			t.Errorf("offset %d: step = %d, want %d", offset, gotStep, current+offset)
+		}
+	}
+
+	code, _ := Code(rfc6238Secret, now)
+	if _, ok := ValidateCode(rfc6238Secret, " "+code+" ", now); !ok {
+		t.Error("surrounding spaces should be ignored")
+	}
+	for _, bad := range []string{"", "12345", "1234567", code[:5] + "x"} {
+		if _, ok := ValidateCode(rfc6238Secret, bad, now); ok {
+			t.Errorf("code %q accepted", bad)
+		}
+	}
+}
+
+func TestValidateUnusedRefusesReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := step(now)
+	code, _ := Code(rfc6238Secret, now)
+	previous, _ := Code(rfc6238Secret, now.Add(-totpPeriod))
+
+	if got, ok := validateUnused(rfc6238Secret, code, now, nil); !ok || got != current {
+		t.Fatalf("first use: step %d, ok %v", got, ok)
+	}
+
+	// The step that was just accepted cannot be used again...
+	last := current
+	if _, ok := validateUnused(rfc6238Secret, code, now, &last); ok {
+		t.Error("replayed code accepted")
+	}
+	// ...nor can an older one still inside the skew window
+	if _, ok := validateUnused(rfc6238Secret, previous, now, &last); ok {
+		t.Error("code older than the last used step accepted")
+	}
+
+	// A later step is fine
+	next, _ := Code(rfc6238Secret, now.Add(totpPeriod))
+	if got, ok := validateUnused(rfc6238Secret, next, now, &last); !ok || got != current+1 {
+		t.Errorf("next step: step %d, ok %v", got, ok)
+	}
+}
@@ -1,8 +1,11 @@
 package middleware
 
 import (
+	"context"
+	"log"
 	"strings"
 
+	"auth-service/internal/revocation"
 	jwtpkg "auth-service/pkg/jwt"
 
 	"github.com/gofiber/fiber/v2"
@@ -10,6 +13,16 @@ import (
 
 // AuthRequired validates JWT and sets user info in context
 func AuthRequired() fiber.Handler {
+	return authRequired(false)
+}
+
+// PasswordChangeAuth is AuthRequired that also accepts the restricted token
+// Login issues when a password has expired; only the change-password route uses it
+func PasswordChangeAuth() fiber.Handler {
+	return authRequired(true)
+}
+
+func authRequired(allowPasswordChange bool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 
@@ -32,8 +45,32 @@ func AuthRequired() fiber.Handler {
 		// Validate JWT
 		claims, err := jwtpkg.ValidateToken(tokenString)
 		if err != nil {
+			restricted, rerr := jwtpkg.ValidateTokenForAudience(tokenString, jwtpkg.PasswordChangeAudience)
+			switch {
+			case rerr != nil:
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "Invalid or expired token",
+				})
+			case !allowPasswordChange:
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error":                    "Password change required",
+					"password_change_required": true,
+				})
+			}
+			claims = restricted
+		}
+
+		// Reject tokens revoked by logout or account deactivation
+		revoked, err := revocation.IsRevoked(context.Background(), claims)
+		if err != nil {
+			log.Printf("❌ Failed to check token revocation: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to verify token",
+			})
+		}
+		if revoked {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid or expired token",
+				"error": "Token has been revoked",
 			})
 		}
 
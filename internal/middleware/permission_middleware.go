@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"log"
+
+	"auth-service/internal/roles"
+	jwtpkg "auth-service/pkg/jwt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequirePermission allows the request only if one of the user's roles grants
+// the permission. Must run after AuthRequired. Permissions are read from the
+// database so grants and revocations apply without a new token.
+func RequirePermission(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(*jwtpkg.CustomClaims)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Unauthorized",
+			})
+		}
+
+		perms, ok := c.Locals("permissions").([]string)
+		if !ok {
+			var err error
+			perms, err = roles.UserPermissions(context.Background(), claims.UserID)
+			if err != nil {
+				log.Printf("❌ Failed to load permissions for user %d: %v", claims.UserID, err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to verify permissions",
+				})
+			}
+			c.Locals("permissions", perms)
+		}
+
+		if !roles.HasPermission(perms, permission) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Missing permission: " + permission,
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestByBodyField(t *testing.T) {
+	app := fiber.New()
+	key := ByBodyField("email")
+	app.Post("/", func(c *fiber.Ctx) error { return c.SendString(key(c)) })
+
+	multipartBody := func(field, value string) (string, io.Reader) {
+		var b bytes.Buffer
+		w := multipart.NewWriter(&b)
+		w.WriteField(field, value)
+		w.Close()
+		return w.FormDataContentType(), &b
+	}
+
+	type bodyCase struct {
+		name        string
+		contentType string
+		body        io.Reader
+		want        string
+	}
+	cases := []bodyCase{
+		{"json", fiber.MIMEApplicationJSON, strings.NewReader(`{"email": " Ann@Example.com "}`), "ann@example.com"},
+		{"json key case", fiber.MIMEApplicationJSON, strings.NewReader(`{"EMAIL": "ann@example.com"}`), "ann@example.com"},
+		{"json charset", fiber.MIMEApplicationJSONCharsetUTF8, strings.NewReader(`{"email": "ann@example.com"}`), "ann@example.com"},
+		{"form", fiber.MIMEApplicationForm, strings.NewReader("email=Ann%40example.com&password=x"), "ann@example.com"},
+		{"form key case", fiber.MIMEApplicationForm, strings.NewReader("Email=ann%40example.com"), "ann@example.com"},
+		{"missing field", fiber.MIMEApplicationJSON, strings.NewReader(`{"password": "x"}`), "-"},
+		{"malformed json", fiber.MIMEApplicationJSON, strings.NewReader(`{"email":`), "-"},
+		{"no body", "", nil, "-"},
+	}
+	ct, body := multipartBody("email", "ann@example.com")
+	cases = append(cases, bodyCase{"multipart", ct, body, "ann@example.com"})
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, "/", tc.body)
+			if tc.contentType != "" {
+				req.Header.Set(fiber.HeaderContentType, tc.contentType)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := io.ReadAll(resp.Body)
+			if string(got) != tc.want {
+				t.Fatalf("key = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
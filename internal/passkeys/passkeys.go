@@ -0,0 +1,410 @@
+package passkeys
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/db"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrCredentialNotFound = errors.New("passkey not found")
+	ErrCloneDetected      = errors.New("passkey sign count did not increase; possible cloned authenticator")
+	ErrNoCredentials      = errors.New("user has no passkeys")
+)
+
+// CredentialInfo describes a stored passkey without its key material
+type CredentialInfo struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	SignCount  uint32     `json:"sign_count"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+var (
+	rpOnce sync.Once
+	rp     *webauthn.WebAuthn
+	rpErr  error
+)
+
+// relyingParty is configured from WEBAUTHN_RP_ID, WEBAUTHN_RP_NAME and the
+// comma-separated WEBAUTHN_ORIGINS
+func relyingParty() (*webauthn.WebAuthn, error) {
+	rpOnce.Do(func() {
+		rp, rpErr = NewRelyingParty(
+			config.Env("WEBAUTHN_RP_ID", "localhost"),
+			config.Env("WEBAUTHN_RP_NAME", "auth-service"),
+			strings.Split(config.Env("WEBAUTHN_ORIGINS", "http://localhost:8080"), ","),
+		)
+	})
+	return rp, rpErr
+}
+
+// NewRelyingParty builds the WebAuthn relying party configuration
+func NewRelyingParty(id, name string, origins []string) (*webauthn.WebAuthn, error) {
+	for i := range origins {
+		origins[i] = strings.TrimSpace(origins[i])
+	}
+	return webauthn.New(&webauthn.Config{
+		RPID:          id,
+		RPDisplayName: name,
+		RPOrigins:     origins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: sessionTTL},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: sessionTTL},
+		},
+	})
+}
+
+// user adapts an account to webauthn.User. handle is a random user handle so
+// the numeric id never reaches authenticators.
+type user struct {
+	id     int
+	handle []byte
+	email  string
+	creds  []webauthn.Credential
+}
+
+func (u *user) WebAuthnID() []byte                         { return u.handle }
+func (u *user) WebAuthnName() string                       { return u.email }
+func (u *user) WebAuthnDisplayName() string                { return u.email }
+func (u *user) WebAuthnCredentials() []webauthn.Credential { return u.creds }
+
+// HasCredentials reports whether the user registered at least one passkey
+func HasCredentials(ctx context.Context, userID int) (bool, error) {
+	var exists bool
+	err := db.DB.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM webauthn_credentials WHERE user_id = $1);", userID).Scan(&exists)
+	return exists, err
+}
+
+// BeginRegistration starts adding a passkey. Existing credentials are
+// excluded so the same authenticator is not registered twice.
+func BeginRegistration(ctx context.Context, userID int) (*protocol.CredentialCreation, string, error) {
+	w, err := relyingParty()
+	if err != nil {
+		return nil, "", err
+	}
+	u, err := loadUser(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	creation, session, err := w.BeginRegistration(u,
+		webauthn.WithExclusions(webauthn.Credentials(u.creds).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := saveSession(ctx, ceremonyRegister, &userID, session)
+	return creation, token, err
+}
+
+// FinishRegistration verifies the attestation in body and stores the credential
+func FinishRegistration(ctx context.Context, userID int, sessionToken, name string, body []byte) (*CredentialInfo, error) {
+	w, err := relyingParty()
+	if err != nil {
+		return nil, err
+	}
+	session, err := takeSession(ctx, sessionToken, ceremonyRegister, &userID)
+	if err != nil {
+		return nil, err
+	}
+	u, err := loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := createCredential(w, u, session, body)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = "Passkey"
+	}
+
+	info := CredentialInfo{Name: name, SignCount: cred.Authenticator.SignCount}
+	err = db.DB.QueryRow(ctx, `
+		INSERT INTO webauthn_credentials (user_id, credential_id, name, credential, sign_count, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, created_at;
+	`, userID, cred.ID, name, data, int64(cred.Authenticator.SignCount)).Scan(&info.ID, &info.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// BeginLogin starts a second-factor assertion limited to the user's passkeys
+func BeginLogin(ctx context.Context, userID int) (*protocol.CredentialAssertion, string, error) {
+	w, err := relyingParty()
+	if err != nil {
+		return nil, "", err
+	}
+	u, err := loadUser(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(u.creds) == 0 {
+		return nil, "", ErrNoCredentials
+	}
+
+	assertion, session, err := w.BeginLogin(u)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := saveSession(ctx, ceremonyLogin, &userID, session)
+	return assertion, token, err
+}
+
+// FinishLogin verifies a second-factor assertion for userID
+func FinishLogin(ctx context.Context, userID int, sessionToken string, body []byte) error {
+	w, err := relyingParty()
+	if err != nil {
+		return err
+	}
+	session, err := takeSession(ctx, sessionToken, ceremonyLogin, &userID)
+	if err != nil {
+		return err
+	}
+	u, err := loadUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	_, cred, err := verifyAssertion(w, session, body, func(handle []byte) (*user, error) {
+		if !bytes.Equal(handle, u.handle) {
+			return nil, ErrCredentialNotFound
+		}
+		return u, nil
+	})
+	if err != nil {
+		return err
+	}
+	return recordUse(ctx, userID, cred)
+}
+
+// BeginPasswordless starts a discoverable-credential login; the authenticator
+// chooses the account and must verify the user (PIN or biometrics)
+func BeginPasswordless(ctx context.Context) (*protocol.CredentialAssertion, string, error) {
+	w, err := relyingParty()
+	if err != nil {
+		return nil, "", err
+	}
+
+	assertion, session, err := w.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := saveSession(ctx, ceremonyPasswordless, nil, session)
+	return assertion, token, err
+}
+
+// FinishPasswordless verifies a discoverable assertion and returns the user it belongs to
+func FinishPasswordless(ctx context.Context, sessionToken string, body []byte) (int, error) {
+	w, err := relyingParty()
+	if err != nil {
+		return 0, err
+	}
+	session, err := takeSession(ctx, sessionToken, ceremonyPasswordless, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	owner, cred, err := verifyAssertion(w, session, body, func(handle []byte) (*user, error) {
+		return loadUserByHandle(ctx, handle)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return owner.id, recordUse(ctx, owner.id, cred)
+}
+
+// List returns the user's passkeys
+func List(ctx context.Context, userID int) ([]CredentialInfo, error) {
+	rows, err := db.DB.Query(ctx, `
+		SELECT id, name, sign_count, created_at, last_used_at
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY id;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	creds := []CredentialInfo{}
+	for rows.Next() {
+		var c CredentialInfo
+		var signCount int64
+		if err := rows.Scan(&c.ID, &c.Name, &signCount, &c.CreatedAt, &c.LastUsedAt); err != nil {
+			return nil, err
+		}
+		c.SignCount = uint32(signCount)
+		creds = append(creds, c)
+	}
+	return creds, rows.Err()
+}
+
+// Delete removes one of the user's passkeys
+func Delete(ctx context.Context, userID, id int) error {
+	tag, err := db.DB.Exec(ctx, "DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2;", id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
+
+// createCredential verifies an attestation response against the session
+func createCredential(w *webauthn.WebAuthn, u *user, session *webauthn.SessionData, body []byte) (*webauthn.Credential, error) {
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(body)
+	if err != nil {
+		return nil, err
+	}
+	return w.CreateCredential(u, *session, parsed)
+}
+
+// verifyAssertion checks an assertion and refuses a sign count that did not
+// advance; the library only flags it, a possible clone fails the login here.
+// resolve maps a user handle to its account: the session's user for a
+// second-factor login, the handle the authenticator returned for passwordless.
+func verifyAssertion(w *webauthn.WebAuthn, session *webauthn.SessionData, body []byte, resolve func(handle []byte) (*user, error)) (*user, *webauthn.Credential, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var u *user
+	var cred *webauthn.Credential
+	if len(session.UserID) > 0 {
+		if u, err = resolve(session.UserID); err != nil {
+			return nil, nil, err
+		}
+		cred, err = w.ValidateLogin(u, *session, parsed)
+	} else {
+		_, cred, err = w.ValidatePasskeyLogin(func(_, handle []byte) (webauthn.User, error) {
+			u, err = resolve(handle)
+			return u, err
+		}, *session, parsed)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if cred.Authenticator.CloneWarning {
+		return nil, nil, ErrCloneDetected
+	}
+	return u, cred, nil
+}
+
+// recordUse stores the new sign count. The WHERE clause repeats the counter
+// check so two concurrent logins cannot both accept the same counter value.
+func recordUse(ctx context.Context, userID int, cred *webauthn.Credential) error {
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+
+	count := int64(cred.Authenticator.SignCount)
+	tag, err := db.DB.Exec(ctx, `
+		UPDATE webauthn_credentials
+		SET credential = $3, sign_count = $4, last_used_at = NOW()
+		WHERE user_id = $1 AND credential_id = $2
+		  AND (sign_count < $4 OR (sign_count = 0 AND $4 = 0));
+	`, userID, cred.ID, data, count)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCloneDetected
+	}
+	return nil
+}
+
+// loadUser returns the account with its passkeys, creating its user handle on first use
+func loadUser(ctx context.Context, userID int) (*user, error) {
+	u := &user{id: userID}
+	err := db.DB.QueryRow(ctx, "SELECT email, webauthn_handle FROM users WHERE id = $1;", userID).Scan(&u.email, &u.handle)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(u.handle) == 0 {
+		handle := make([]byte, 32)
+		if _, err := rand.Read(handle); err != nil {
+			return nil, err
+		}
+		// Another request may have set it concurrently; keep whichever won
+		err = db.DB.QueryRow(ctx, `
+			UPDATE users SET webauthn_handle = COALESCE(webauthn_handle, $2)
+			WHERE id = $1
+			RETURNING webauthn_handle;
+		`, userID, handle).Scan(&u.handle)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	u.creds, err = loadCredentials(ctx, userID)
+	return u, err
+}
+
+func loadUserByHandle(ctx context.Context, handle []byte) (*user, error) {
+	var userID int
+	var isActive bool
+	err := db.DB.QueryRow(ctx, "SELECT id, is_active FROM users WHERE webauthn_handle = $1;", handle).Scan(&userID, &isActive)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !isActive) {
+		return nil, ErrCredentialNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return loadUser(ctx, userID)
+}
+
+func loadCredentials(ctx context.Context, userID int) ([]webauthn.Credential, error) {
+	rows, err := db.DB.Query(ctx, "SELECT credential, sign_count FROM webauthn_credentials WHERE user_id = $1;", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var creds []webauthn.Credential
+	for rows.Next() {
+		var data []byte
+		var signCount int64
+		if err := rows.Scan(&data, &signCount); err != nil {
+			return nil, err
+		}
+		var c webauthn.Credential
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, err
+		}
+		// The column is authoritative for the counter
+		c.Authenticator.SignCount = uint32(signCount)
+		creds = append(creds, c)
+	}
+	return creds, rows.Err()
+}
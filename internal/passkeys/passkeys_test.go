@@ -0,0 +1,246 @@
+package passkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	testRPID   = "auth.example.com"
+	testOrigin = "https://auth.example.com"
+)
+
+// softAuthenticator is an in-memory ES256 authenticator producing "none"
+// attestations, so ceremonies can be tested without hardware
+type softAuthenticator struct {
+	key     *ecdsa.PrivateKey
+	credID  []byte
+	handle  []byte
+	counter uint32
+	origin  string
+}
+
+func newSoftAuthenticator(t *testing.T, handle []byte) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credID := make([]byte, 16)
+	rand.Read(credID)
+	return &softAuthenticator{key: key, credID: credID, handle: handle, origin: testOrigin}
+}
+
+const (
+	flagUP = 0x01
+	flagUV = 0x04
+	flagAT = 0x40
+)
+
+func (a *softAuthenticator) authData(flags byte) []byte {
+	rpHash := sha256.Sum256([]byte(testRPID))
+	data := append(rpHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.counter)
+}
+
+func (a *softAuthenticator) clientData(typ string, challenge protocol.URLEncodedBase64) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": challenge.String(),
+		"origin":    a.origin,
+	})
+	return data
+}
+
+// create answers navigator.credentials.create()
+func (a *softAuthenticator) create(t *testing.T, creation *protocol.CredentialCreation) []byte {
+	t.Helper()
+	cose, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authData := a.authData(flagUP | flagUV | flagAT)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credID)))
+	authData = append(authData, a.credID...)
+	authData = append(authData, cose...)
+
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.response(map[string]string{
+		"clientDataJSON":    b64(a.clientData("webauthn.create", creation.Response.Challenge)),
+		"attestationObject": b64(attestation),
+	})
+}
+
+// get answers navigator.credentials.get()
+func (a *softAuthenticator) get(t *testing.T, assertion *protocol.CredentialAssertion, flags byte) []byte {
+	t.Helper()
+	a.counter++
+	authData := a.authData(flags)
+	clientData := a.clientData("webauthn.get", assertion.Response.Challenge)
+
+	clientHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.response(map[string]string{
+		"clientDataJSON":    b64(clientData),
+		"authenticatorData": b64(authData),
+		"signature":         b64(sig),
+		"userHandle":        b64(a.handle),
+	})
+}
+
+func (a *softAuthenticator) response(resp map[string]string) []byte {
+	body, _ := json.Marshal(map[string]any{
+		"id":       b64(a.credID),
+		"rawId":    b64(a.credID),
+		"type":     "public-key",
+		"response": resp,
+	})
+	return body
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// register runs a full registration ceremony and returns the stored user
+func register(t *testing.T, w *webauthn.WebAuthn) (*user, *softAuthenticator) {
+	t.Helper()
+	u := &user{id: 1, handle: []byte("user-handle-0001"), email: "alice@example.com"}
+	auth := newSoftAuthenticator(t, u.handle)
+
+	creation, session, err := w.BeginRegistration(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := createCredential(w, u, session, auth.create(t, creation))
+	if err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+	u.creds = append(u.creds, *cred)
+	return u, auth
+}
+
+func newTestRP(t *testing.T) *webauthn.WebAuthn {
+	t.Helper()
+	w, err := NewRelyingParty(testRPID, "Test", []string{testOrigin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestSecondFactorLogin(t *testing.T) {
+	w := newTestRP(t)
+	u, auth := register(t, w)
+	resolve := func([]byte) (*user, error) { return u, nil }
+
+	assertion, session, err := w.BeginLogin(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, cred, err := verifyAssertion(w, session, auth.get(t, assertion, flagUP), resolve)
+	if err != nil {
+		t.Fatalf("assertion failed: %v", err)
+	}
+	if got.id != u.id || cred.Authenticator.SignCount != 1 {
+		t.Fatalf("got user %d, sign count %d", got.id, cred.Authenticator.SignCount)
+	}
+}
+
+func TestSignCountMustIncrease(t *testing.T) {
+	w := newTestRP(t)
+	u, auth := register(t, w)
+	resolve := func([]byte) (*user, error) { return u, nil }
+
+	// Store a counter ahead of the authenticator, as a cloned key would see
+	u.creds[0].Authenticator.SignCount = 10
+
+	assertion, session, _ := w.BeginLogin(u)
+	_, _, err := verifyAssertion(w, session, auth.get(t, assertion, flagUP), resolve)
+	if !errors.Is(err, ErrCloneDetected) {
+		t.Fatalf("expected clone detection, got %v", err)
+	}
+}
+
+func TestPasswordlessLogin(t *testing.T) {
+	w := newTestRP(t)
+	u, auth := register(t, w)
+	resolve := func(handle []byte) (*user, error) {
+		if string(handle) != string(u.handle) {
+			return nil, ErrCredentialNotFound
+		}
+		return u, nil
+	}
+
+	assertion, session, err := w.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := verifyAssertion(w, session, auth.get(t, assertion, flagUP|flagUV), resolve)
+	if err != nil {
+		t.Fatalf("passwordless assertion failed: %v", err)
+	}
+	if got.id != u.id {
+		t.Fatalf("resolved user %d, want %d", got.id, u.id)
+	}
+
+	// Passwordless must not accept an assertion without user verification
+	assertion, session, _ = w.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if _, _, err := verifyAssertion(w, session, auth.get(t, assertion, flagUP), resolve); err == nil {
+		t.Fatal("assertion without user verification was accepted")
+	}
+}
+
+func TestRejectsWrongOriginAndChallenge(t *testing.T) {
+	w := newTestRP(t)
+	u, auth := register(t, w)
+	resolve := func([]byte) (*user, error) { return u, nil }
+
+	// Phishing site relaying the challenge
+	assertion, session, _ := w.BeginLogin(u)
+	auth.origin = "https://auth.example.com.evil.test"
+	if _, _, err := verifyAssertion(w, session, auth.get(t, assertion, flagUP), resolve); err == nil {
+		t.Fatal("assertion from a foreign origin was accepted")
+	}
+	auth.origin = testOrigin
+
+	// Answer to a different ceremony's challenge
+	other, _, _ := w.BeginLogin(u)
+	_, session, _ = w.BeginLogin(u)
+	if _, _, err := verifyAssertion(w, session, auth.get(t, other, flagUP), resolve); err == nil {
+		t.Fatal("assertion for another challenge was accepted")
+	}
+}
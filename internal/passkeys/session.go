@@ -0,0 +1,73 @@
+package passkeys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"auth-service/internal/db"
+	"auth-service/internal/utils"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// Ceremonies a session can belong to; a session is only valid for its own
+const (
+	ceremonyRegister     = "register"
+	ceremonyLogin        = "login"
+	ceremonyPasswordless = "passwordless"
+)
+
+// sessionTTL bounds how long a browser has to answer a challenge
+const sessionTTL = 5 * time.Minute
+
+var ErrSessionInvalid = errors.New("invalid or expired WebAuthn session")
+
+// saveSession stores the ceremony state server-side and returns an opaque
+// token for the client to send back with its response
+func saveSession(ctx context.Context, ceremony string, userID *int, session *webauthn.SessionData) (string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.DB.Exec(ctx, `
+		INSERT INTO webauthn_sessions (token_hash, ceremony, user_id, data, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW());
+	`, utils.HashToken(token), ceremony, userID, data, time.Now().UTC().Add(sessionTTL))
+	if err != nil {
+		return "", err
+	}
+
+	_, _ = db.DB.Exec(ctx, "DELETE FROM webauthn_sessions WHERE expires_at < $1;", time.Now().UTC())
+	return token, nil
+}
+
+// takeSession consumes a session; it can be answered only once
+func takeSession(ctx context.Context, token, ceremony string, userID *int) (*webauthn.SessionData, error) {
+	var data []byte
+	var owner *int
+	err := db.DB.QueryRow(ctx, `
+		DELETE FROM webauthn_sessions
+		WHERE token_hash = $1 AND ceremony = $2 AND expires_at > $3
+		RETURNING user_id, data;
+	`, utils.HashToken(token), ceremony, time.Now().UTC()).Scan(&owner, &data)
+	if err != nil {
+		return nil, ErrSessionInvalid
+	}
+
+	if (userID == nil) != (owner == nil) || (userID != nil && *userID != *owner) {
+		return nil, ErrSessionInvalid
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
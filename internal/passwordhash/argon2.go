@@ -0,0 +1,138 @@
+package passwordhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Params are the argon2id cost parameters
+type Argon2Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2 follows the OWASP recommendation for argon2id
+var DefaultArgon2 = Argon2Params{Memory: 64 * 1024, Iterations: 3, Parallelism: 2, SaltLength: 16, KeyLength: 32}
+
+// Upper bounds on argon2id parameters. Stored hashes are parsed with the
+// same limits, so a crafted hash cannot make a login allocate gigabytes or
+// spin for minutes.
+const (
+	maxArgon2Memory     = 1024 * 1024 // KiB (1 GiB)
+	maxArgon2Iterations = 100
+	maxArgon2SaltLength = 64
+	maxArgon2KeyLength  = 128
+)
+
+func (p Argon2Params) validate() error {
+	if p.Memory < 8*uint32(p.Parallelism) || p.Iterations < 1 || p.Parallelism < 1 {
+		return errors.New("invalid argon2id parameters")
+	}
+	if p.Memory > maxArgon2Memory || p.Iterations > maxArgon2Iterations {
+		return fmt.Errorf("argon2id memory is limited to %d KiB and iterations to %d", maxArgon2Memory, maxArgon2Iterations)
+	}
+	if p.SaltLength < 8 || p.KeyLength < 16 {
+		return errors.New("argon2id salt must be at least 8 bytes and key at least 16 bytes")
+	}
+	if p.SaltLength > maxArgon2SaltLength || p.KeyLength > maxArgon2KeyLength {
+		return fmt.Errorf("argon2id salt is limited to %d bytes and key to %d bytes", maxArgon2SaltLength, maxArgon2KeyLength)
+	}
+	return nil
+}
+
+// parsedArgon2id is a decoded "$argon2id$v=19$m=...,t=...,p=...[,keyid=...]$salt$hash"
+type parsedArgon2id struct {
+	params Argon2Params
+	keyID  string
+	salt   []byte
+	key    []byte
+}
+
+func hashArgon2id(password string, p Argon2Params, keyID string) (string, error) {
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", p.Memory, p.Iterations, p.Parallelism)
+	if keyID != "" {
+		// keyid is the PHC parameter for naming the secret key (our pepper)
+		params += ",keyid=" + keyID
+	}
+	return fmt.Sprintf("$argon2id$v=%d$%s$%s$%s", argon2.Version, params,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func parseArgon2id(encoded string) (*parsedArgon2id, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrUnknownFormat
+	}
+	if parts[2] != "v="+strconv.Itoa(argon2.Version) {
+		return nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	var out parsedArgon2id
+	seen := map[string]bool{}
+	for _, kv := range strings.Split(parts[3], ",") {
+		name, value, _ := strings.Cut(kv, "=")
+		if seen[name] {
+			return nil, ErrUnknownFormat
+		}
+		seen[name] = true
+		if name == "keyid" {
+			if value == "" {
+				return nil, ErrUnknownFormat
+			}
+			out.keyID = value
+			continue
+		}
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, ErrUnknownFormat
+		}
+		switch name {
+		case "m":
+			out.params.Memory = uint32(n)
+		case "t":
+			out.params.Iterations = uint32(n)
+		case "p":
+			if n > 255 {
+				return nil, ErrUnknownFormat
+			}
+			out.params.Parallelism = uint8(n)
+		default:
+			return nil, ErrUnknownFormat
+		}
+	}
+
+	var err error
+	if out.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnknownFormat
+	}
+	if out.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, ErrUnknownFormat
+	}
+	out.params.SaltLength = uint32(len(out.salt))
+	out.params.KeyLength = uint32(len(out.key))
+	if out.params.validate() != nil {
+		return nil, ErrUnknownFormat
+	}
+	return &out, nil
+}
+
+func (p *parsedArgon2id) verify(password string) bool {
+	key := argon2.IDKey([]byte(password), p.salt, p.params.Iterations, p.params.Memory, p.params.Parallelism, p.params.KeyLength)
+	return subtle.ConstantTimeCompare(key, p.key) == 1
+}
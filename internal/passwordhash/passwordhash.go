@@ -0,0 +1,179 @@
+package passwordhash
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"auth-service/internal/config"
+)
+
+// Algorithms new hashes can be created with
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unrecognised password hash format")
+	ErrUnknownPepper = errors.New("password hash uses an unknown pepper")
+)
+
+// Config selects the algorithm and parameters for new hashes. Hashes made
+// with any supported algorithm or parameters keep verifying.
+type Config struct {
+	Algorithm  string
+	Argon2     Argon2Params
+	BcryptCost int
+
+	// Pepper is a server-side secret mixed into argon2id hashes, kept out of
+	// the database; bcrypt hashes have no field to name it, so it cannot be
+	// combined with bcrypt. PreviousPepper still verifies during a pepper
+	// rotation (or while moving peppered argon2id hashes to bcrypt).
+	Pepper         string
+	PreviousPepper string
+}
+
+// Hasher creates and verifies PHC-format password hashes
+type Hasher struct {
+	cfg     Config
+	peppers map[string][]byte // key id -> pepper
+	keyID   string            // id of cfg.Pepper, "" without a pepper
+}
+
+// New validates cfg and builds a Hasher
+func New(cfg Config) (*Hasher, error) {
+	switch cfg.Algorithm {
+	case Argon2id:
+		if err := cfg.Argon2.validate(); err != nil {
+			return nil, err
+		}
+	case Bcrypt:
+		if err := validateBcryptCost(cfg.BcryptCost); err != nil {
+			return nil, err
+		}
+		if cfg.Pepper != "" {
+			return nil, errors.New("PASSWORD_PEPPER is only supported with argon2id")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", cfg.Algorithm)
+	}
+
+	h := &Hasher{cfg: cfg, peppers: map[string][]byte{}}
+	for _, p := range []string{cfg.PreviousPepper, cfg.Pepper} {
+		if p != "" {
+			h.peppers[pepperKeyID(p)] = []byte(p)
+		}
+	}
+	if cfg.Pepper != "" {
+		h.keyID = pepperKeyID(cfg.Pepper)
+	}
+	return h, nil
+}
+
+// FromEnv reads the hashing configuration:
+// PASSWORD_HASH_ALGORITHM (argon2id or bcrypt), ARGON2_MEMORY_KIB,
+// ARGON2_ITERATIONS, ARGON2_PARALLELISM, BCRYPT_COST, PASSWORD_PEPPER and
+// PASSWORD_PEPPER_PREVIOUS
+func FromEnv() (*Hasher, error) {
+	return New(Config{
+		Algorithm: strings.ToLower(config.Env("PASSWORD_HASH_ALGORITHM", Argon2id)),
+		Argon2: Argon2Params{
+			Memory:      uint32(config.EnvInt("ARGON2_MEMORY_KIB", int(DefaultArgon2.Memory))),
+			Iterations:  uint32(config.EnvInt("ARGON2_ITERATIONS", int(DefaultArgon2.Iterations))),
+			Parallelism: uint8(config.EnvInt("ARGON2_PARALLELISM", int(DefaultArgon2.Parallelism))),
+			SaltLength:  DefaultArgon2.SaltLength,
+			KeyLength:   DefaultArgon2.KeyLength,
+		},
+		BcryptCost:     config.EnvInt("BCRYPT_COST", DefaultBcryptCost),
+		Pepper:         config.Env("PASSWORD_PEPPER", ""),
+		PreviousPepper: config.Env("PASSWORD_PEPPER_PREVIOUS", ""),
+	})
+}
+
+var (
+	mu      sync.RWMutex
+	current = mustNew(Config{Algorithm: Argon2id, Argon2: DefaultArgon2, BcryptCost: DefaultBcryptCost})
+)
+
+// SetDefault installs the hasher used by the package-level functions
+func SetDefault(h *Hasher) {
+	mu.Lock()
+	current = h
+	mu.Unlock()
+}
+
+// Default returns the hasher installed by SetDefault
+func Default() *Hasher {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Hash creates a hash of password with the configured algorithm
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.cfg.Algorithm == Bcrypt {
+		return hashBcrypt(password, h.cfg.BcryptCost)
+	}
+	return hashArgon2id(h.pepper(password, h.keyID), h.cfg.Argon2, h.keyID)
+}
+
+// Verify checks password against an encoded hash of any supported format
+func (h *Hasher) Verify(password, encoded string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		p, err := parseArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+		if p.keyID != "" && h.peppers[p.keyID] == nil {
+			return false, ErrUnknownPepper
+		}
+		return p.verify(h.pepper(password, p.keyID)), nil
+	case isBcrypt(encoded):
+		return verifyBcrypt(password, encoded)
+	default:
+		return false, ErrUnknownFormat
+	}
+}
+
+// NeedsRehash reports whether encoded was made with another algorithm,
+// other parameters or another pepper than new hashes would be
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	switch h.cfg.Algorithm {
+	case Argon2id:
+		p, err := parseArgon2id(encoded)
+		return err != nil || p.params != h.cfg.Argon2 || p.keyID != h.keyID
+	case Bcrypt:
+		return !isBcrypt(encoded) || bcryptCost(encoded) != h.cfg.BcryptCost
+	}
+	return false
+}
+
+// pepper mixes the pepper with the given key id into password
+func (h *Hasher) pepper(password, keyID string) string {
+	if keyID == "" {
+		return password
+	}
+	mac := hmac.New(sha256.New, h.peppers[keyID])
+	mac.Write([]byte(password))
+	return string(mac.Sum(nil))
+}
+
+// pepperKeyID identifies a pepper in stored hashes without revealing it
+func pepperKeyID(pepper string) string {
+	sum := sha256.Sum256([]byte("auth-service pepper id:" + pepper))
+	return base64.RawStdEncoding.EncodeToString(sum[:6])
+}
+
+func mustNew(cfg Config) *Hasher {
+	h, err := New(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
@@ -0,0 +1,121 @@
+package passwordhash
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// cheapArgon2 keeps unit tests fast; benchmarks use DefaultArgon2
+var cheapArgon2 = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHashVerifyAndRehash(t *testing.T) {
+	argon, err := New(Config{Algorithm: Argon2id, Argon2: cheapArgon2, Pepper: "pepper-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := New(Config{Algorithm: Bcrypt, BcryptCost: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, _ := legacy.Hash("correct horse")
+	if ok, err := argon.Verify("correct horse", old); !ok || err != nil {
+		t.Fatalf("bcrypt hash did not verify: %v", err)
+	}
+	if !argon.NeedsRehash(old) {
+		t.Fatal("bcrypt hash should be upgraded to argon2id")
+	}
+
+	fresh, _ := argon.Hash("correct horse")
+	if ok, _ := argon.Verify("correct horse", fresh); !ok {
+		t.Fatal("argon2id hash did not verify")
+	}
+	if ok, _ := argon.Verify("wrong horse", fresh); ok {
+		t.Fatal("wrong password verified")
+	}
+	if argon.NeedsRehash(fresh) {
+		t.Fatal("fresh hash flagged for rehash")
+	}
+
+	// Rotating the pepper keeps old hashes working until they are rehashed
+	rotated, _ := New(Config{Algorithm: Argon2id, Argon2: cheapArgon2, Pepper: "pepper-2", PreviousPepper: "pepper-1"})
+	if ok, _ := rotated.Verify("correct horse", fresh); !ok {
+		t.Fatal("hash with the previous pepper did not verify")
+	}
+	if !rotated.NeedsRehash(fresh) {
+		t.Fatal("hash with the previous pepper should be rehashed")
+	}
+	unpeppered, _ := New(Config{Algorithm: Argon2id, Argon2: cheapArgon2})
+	if _, err := unpeppered.Verify("correct horse", fresh); !errors.Is(err, ErrUnknownPepper) {
+		t.Fatalf("verify without the pepper = %v, want ErrUnknownPepper", err)
+	}
+}
+
+func TestPepperRequiresArgon2id(t *testing.T) {
+	if _, err := New(Config{Algorithm: Bcrypt, BcryptCost: 4, Pepper: "pepper-1"}); err == nil {
+		t.Fatal("bcrypt with a pepper was accepted")
+	}
+
+	// Moving from peppered argon2id to bcrypt keeps the old pepper for verifying
+	argon, _ := New(Config{Algorithm: Argon2id, Argon2: cheapArgon2, Pepper: "pepper-1"})
+	old, _ := argon.Hash("correct horse")
+	legacy, err := New(Config{Algorithm: Bcrypt, BcryptCost: 4, PreviousPepper: "pepper-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := legacy.Verify("correct horse", old); !ok || err != nil {
+		t.Fatalf("peppered argon2id hash did not verify: %v", err)
+	}
+	if !legacy.NeedsRehash(old) {
+		t.Fatal("argon2id hash should be rehashed with bcrypt")
+	}
+}
+
+func TestParseArgon2id(t *testing.T) {
+	salt := "c29tZXNhbHRzb21lc2FsdA"                     // 16 bytes
+	key := "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U" // 32 bytes
+	valid := "$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$" + key
+
+	p, err := parseArgon2id(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.params != cheapArgon2 || p.keyID != "" {
+		t.Fatalf("parsed %+v", p)
+	}
+	if p, err := parseArgon2id("$argon2id$v=19$m=1024,t=1,p=1,keyid=abc$" + salt + "$" + key); err != nil || p.keyID != "abc" {
+		t.Fatalf("keyid: %+v, %v", p, err)
+	}
+
+	for name, encoded := range map[string]string{
+		"empty":               "",
+		"other algorithm":     "$argon2i$v=19$m=1024,t=1,p=1$" + salt + "$" + key,
+		"old version":         "$argon2id$v=16$m=1024,t=1,p=1$" + salt + "$" + key,
+		"missing part":        "$argon2id$v=19$m=1024,t=1,p=1$" + salt,
+		"extra part":          valid + "$" + key,
+		"missing memory":      "$argon2id$v=19$t=1,p=1$" + salt + "$" + key,
+		"missing parallelism": "$argon2id$v=19$m=1024,t=1$" + salt + "$" + key,
+		"unknown param":       "$argon2id$v=19$m=1024,t=1,p=1,x=1$" + salt + "$" + key,
+		"duplicate param":     "$argon2id$v=19$m=1024,t=1,p=1,m=8$" + salt + "$" + key,
+		"duplicate keyid":     "$argon2id$v=19$m=1024,t=1,p=1,keyid=a,keyid=b$" + salt + "$" + key,
+		"empty keyid":         "$argon2id$v=19$m=1024,t=1,p=1,keyid=$" + salt + "$" + key,
+		"negative":            "$argon2id$v=19$m=-1,t=1,p=1$" + salt + "$" + key,
+		"not a number":        "$argon2id$v=19$m=lots,t=1,p=1$" + salt + "$" + key,
+		"overflowing memory":  "$argon2id$v=19$m=4294967296,t=1,p=1$" + salt + "$" + key,
+		"huge memory":         "$argon2id$v=19$m=4294967295,t=1,p=1$" + salt + "$" + key,
+		"huge iterations":     "$argon2id$v=19$m=1024,t=1000000,p=1$" + salt + "$" + key,
+		"zero iterations":     "$argon2id$v=19$m=1024,t=0,p=1$" + salt + "$" + key,
+		"huge parallelism":    "$argon2id$v=19$m=1024,t=1,p=256$" + salt + "$" + key,
+		"zero parallelism":    "$argon2id$v=19$m=1024,t=1,p=0$" + salt + "$" + key,
+		"bad salt encoding":   "$argon2id$v=19$m=1024,t=1,p=1$not*base64$" + key,
+		"padded salt":         "$argon2id$v=19$m=1024,t=1,p=1$" + salt + "==$" + key,
+		"short salt":          "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$" + key,
+		"short key":           "$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$a2V5",
+		"huge key":            "$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$" + strings.Repeat("a2V5", 100),
+	} {
+		if _, err := parseArgon2id(encoded); err == nil {
+			t.Errorf("%s: %q was accepted", name, encoded)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package passwordhash
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"auth-service/internal/config"
+)
+
+// ErrOverloaded is returned when the hashing queue is full or a job waited
+// longer than the queue timeout; callers should answer 503 with RetryAfter
+var ErrOverloaded = errors.New("password hashing queue is full")
+
+// Pool runs password hashing on a fixed number of workers so a burst of
+// logins cannot take every CPU core (or, with argon2id, all memory)
+type Pool struct {
+	jobs    chan *job
+	workers int
+	timeout time.Duration // longest a job may wait in the queue
+
+	queued    atomic.Int64
+	busy      atomic.Int64
+	completed atomic.Uint64
+	rejected  atomic.Uint64
+	expired   atomic.Uint64
+	waitNanos atomic.Int64 // total time completed jobs spent queued
+	runNanos  atomic.Int64 // total time completed jobs spent running
+}
+
+type job struct {
+	fn       func()
+	queuedAt time.Time
+	state    atomic.Int32 // jobQueued, then jobRunning or jobAbandoned
+	done     chan struct{}
+}
+
+const (
+	jobQueued int32 = iota
+	jobRunning
+	jobAbandoned
+)
+
+// Stats is a snapshot of the pool's metrics
+type Stats struct {
+	Workers       int     `json:"workers"`
+	QueueCapacity int     `json:"queue_capacity"`
+	Queued        int64   `json:"queued"`
+	Busy          int64   `json:"busy"`
+	Completed     uint64  `json:"completed"`
+	Rejected      uint64  `json:"rejected"` // queue was full
+	Expired       uint64  `json:"expired"`  // caller gave up while the job was queued
+	AvgWaitMs     float64 `json:"avg_wait_ms"`
+	AvgRunMs      float64 `json:"avg_run_ms"`
+}
+
+// NewPool starts workers that take jobs from a queue holding up to queueSize
+// jobs; a job queued longer than timeout (0 for no limit) is dropped
+func NewPool(workers, queueSize int, timeout time.Duration) *Pool {
+	workers = max(workers, 1)
+	p := &Pool{jobs: make(chan *job, max(queueSize, 0)), workers: workers, timeout: timeout}
+	for range workers {
+		go p.work()
+	}
+	return p
+}
+
+// PoolFromEnv sizes a pool from HASH_WORKERS (default: one per CPU),
+// HASH_QUEUE_SIZE (default: 16 per worker) and HASH_QUEUE_TIMEOUT_MS
+func PoolFromEnv() *Pool {
+	workers := config.EnvInt("HASH_WORKERS", runtime.GOMAXPROCS(0))
+	queueSize := config.EnvInt("HASH_QUEUE_SIZE", 16*workers)
+	timeout := time.Duration(config.EnvInt("HASH_QUEUE_TIMEOUT_MS", 5000)) * time.Millisecond
+	return NewPool(workers, queueSize, timeout)
+}
+
+func (p *Pool) work() {
+	for j := range p.jobs {
+		p.queued.Add(-1)
+		if !j.state.CompareAndSwap(jobQueued, jobRunning) {
+			// The caller gave up; don't burn CPU on an answer nobody reads
+			continue
+		}
+
+		start := time.Now()
+		p.busy.Add(1)
+		j.fn()
+		p.busy.Add(-1)
+
+		p.waitNanos.Add(int64(start.Sub(j.queuedAt)))
+		p.runNanos.Add(int64(time.Since(start)))
+		p.completed.Add(1)
+		close(j.done)
+	}
+}
+
+// Do runs fn on a worker and waits for it. It fails fast with ErrOverloaded
+// when the queue is full, or when fn is still queued after the queue timeout,
+// instead of piling up more work.
+func (p *Pool) Do(ctx context.Context, fn func()) error {
+	j := &job{fn: fn, queuedAt: time.Now(), done: make(chan struct{})}
+	p.queued.Add(1)
+	select {
+	case p.jobs <- j:
+	default:
+		p.queued.Add(-1)
+		p.rejected.Add(1)
+		return ErrOverloaded
+	}
+
+	var timeout <-chan time.Time
+	if p.timeout > 0 {
+		timer := time.NewTimer(p.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-j.done:
+		return nil
+	case <-timeout:
+		if j.state.CompareAndSwap(jobQueued, jobAbandoned) {
+			p.expired.Add(1)
+			return ErrOverloaded
+		}
+	case <-ctx.Done():
+		if j.state.CompareAndSwap(jobQueued, jobAbandoned) {
+			p.expired.Add(1)
+			return ctx.Err()
+		}
+	}
+
+	// fn already started; the timeout only bounds time spent queued
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns the current metrics
+func (p *Pool) Stats() Stats {
+	s := Stats{
+		Workers:       p.workers,
+		QueueCapacity: cap(p.jobs),
+		Queued:        max(p.queued.Load(), 0),
+		Busy:          p.busy.Load(),
+		Completed:     p.completed.Load(),
+		Rejected:      p.rejected.Load(),
+		Expired:       p.expired.Load(),
+	}
+	if s.Completed > 0 {
+		s.AvgWaitMs = float64(p.waitNanos.Load()) / float64(s.Completed) / 1e6
+		s.AvgRunMs = float64(p.runNanos.Load()) / float64(s.Completed) / 1e6
+	}
+	return s
+}
+
+// RetryAfter estimates how long until the queue has drained
+func (p *Pool) RetryAfter() time.Duration {
+	s := p.Stats()
+	avgRun := time.Duration(s.AvgRunMs * float64(time.Millisecond))
+	wait := time.Duration(s.Queued/int64(p.workers)+1) * avgRun
+	return max(wait, time.Second)
+}
+
+var (
+	poolMu      sync.Mutex
+	currentPool *Pool
+)
+
+// SetPool installs the pool used by Hash and Verify
+func SetPool(p *Pool) {
+	poolMu.Lock()
+	currentPool = p
+	poolMu.Unlock()
+}
+
+// DefaultPool returns the installed pool, starting one sized from the
+// environment on first use
+func DefaultPool() *Pool {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	if currentPool == nil {
+		currentPool = PoolFromEnv()
+	}
+	return currentPool
+}
+
+// Hash hashes password with the default hasher on the default pool
+func Hash(ctx context.Context, password string) (string, error) {
+	var hash string
+	var err error
+	if perr := DefaultPool().Do(ctx, func() { hash, err = Default().Hash(password) }); perr != nil {
+		return "", perr
+	}
+	return hash, err
+}
+
+// Verify checks password with the default hasher on the default pool
+func Verify(ctx context.Context, password, encoded string) (bool, error) {
+	var ok bool
+	var err error
+	if perr := DefaultPool().Do(ctx, func() { ok, err = Default().Verify(password, encoded) }); perr != nil {
+		return false, perr
+	}
+	return ok, err
+}
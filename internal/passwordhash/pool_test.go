@@ -0,0 +1,153 @@
+package passwordhash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockWorker occupies the only worker of p until the returned func is called
+func blockWorker(t *testing.T, p *Pool) (release func()) {
+	t.Helper()
+	started, unblock := make(chan struct{}), make(chan struct{})
+	go p.Do(context.Background(), func() {
+		close(started)
+		<-unblock
+	})
+	<-started
+	return func() { close(unblock) }
+}
+
+func TestPoolShedsLoadWhenQueueFull(t *testing.T) {
+	p := NewPool(1, 1, 0)
+	release := blockWorker(t, p)
+
+	// Fills the single queue slot
+	queued := make(chan error)
+	go func() { queued <- p.Do(context.Background(), func() {}) }()
+	for p.Stats().Queued != 1 {
+		runtime.Gosched()
+	}
+
+	if err := p.Do(context.Background(), func() { t.Error("rejected job ran") }); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("Do on a full queue = %v, want ErrOverloaded", err)
+	}
+
+	release()
+	if err := <-queued; err != nil {
+		t.Fatalf("queued job: %v", err)
+	}
+	if s := p.Stats(); s.Rejected != 1 || s.Completed != 2 {
+		t.Fatalf("stats %+v, want 1 rejected and 2 completed", s)
+	}
+	if p.RetryAfter() < time.Second {
+		t.Fatal("RetryAfter must be at least a second")
+	}
+}
+
+func TestPoolDropsJobsThatWaitTooLong(t *testing.T) {
+	p := NewPool(1, 4, 20*time.Millisecond)
+	release := blockWorker(t, p)
+
+	var ran atomic.Bool
+	if err := p.Do(context.Background(), func() { ran.Store(true) }); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("Do after queue timeout = %v, want ErrOverloaded", err)
+	}
+	release()
+
+	// The next job runs only after the expired one has been skipped
+	if err := p.Do(context.Background(), func() {}); err != nil {
+		t.Fatal(err)
+	}
+	if ran.Load() {
+		t.Fatal("job ran after its caller gave up")
+	}
+	if s := p.Stats(); s.Expired != 1 {
+		t.Fatalf("stats %+v, want 1 expired", s)
+	}
+}
+
+// The benchmarks compare hashing inline on every request goroutine, as
+// handlers did before the pool, with hashing through a pool of one worker
+// per CPU. Run with:
+//
+//	go test ./internal/passwordhash -run '^$' -bench . -benchtime 50x
+//
+// Besides throughput, each reports p50/p99 latency, the peak number of hashes
+// running at once (memory use with argon2id grows with it) and, for the pool,
+// the share of requests shed with ErrOverloaded.
+func BenchmarkHashing(b *testing.B) {
+	h, err := New(Config{Algorithm: Argon2id, Argon2: DefaultArgon2})
+	if err != nil {
+		b.Fatal(err)
+	}
+	cpus := runtime.GOMAXPROCS(0)
+
+	levels := slices.Compact([]int{1, cpus, 4 * cpus, 16 * cpus})
+	for _, concurrency := range levels {
+		b.Run(fmt.Sprintf("inline/clients=%d", concurrency), func(b *testing.B) {
+			benchmarkClients(b, concurrency, func(fn func()) error { fn(); return nil }, h)
+		})
+		b.Run(fmt.Sprintf("pool/clients=%d", concurrency), func(b *testing.B) {
+			p := NewPool(cpus, 4*cpus, 0)
+			benchmarkClients(b, concurrency, func(fn func()) error { return p.Do(context.Background(), fn) }, h)
+		})
+	}
+}
+
+// benchmarkClients has concurrency goroutines hash passwords through run
+// until b.N hashes were attempted
+func benchmarkClients(b *testing.B, concurrency int, run func(func()) error, h *Hasher) {
+	var (
+		next      atomic.Int64
+		running   atomic.Int64
+		peak      atomic.Int64
+		shed      atomic.Int64
+		mu        sync.Mutex
+		latencies = make([]time.Duration, 0, b.N)
+		wg        sync.WaitGroup
+	)
+
+	b.ResetTimer()
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for next.Add(1) <= int64(b.N) {
+				start := time.Now()
+				err := run(func() {
+					n := running.Add(1)
+					for cur := peak.Load(); n > cur && !peak.CompareAndSwap(cur, n); cur = peak.Load() {
+					}
+					if _, err := h.Hash("benchmark password"); err != nil {
+						b.Error(err)
+					}
+					running.Add(-1)
+				})
+				if errors.Is(err, ErrOverloaded) {
+					shed.Add(1)
+					continue
+				}
+				mu.Lock()
+				latencies = append(latencies, time.Since(start))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	b.StopTimer()
+
+	slices.Sort(latencies)
+	if n := len(latencies); n > 0 {
+		b.ReportMetric(float64(latencies[n/2].Microseconds())/1000, "p50-ms")
+		b.ReportMetric(float64(latencies[n*99/100].Microseconds())/1000, "p99-ms")
+	}
+	b.ReportMetric(float64(peak.Load()), "peak-running")
+	b.ReportMetric(float64(shed.Load())/float64(b.N)*100, "shed-%")
+}
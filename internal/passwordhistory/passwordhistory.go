@@ -0,0 +1,92 @@
+package passwordhistory
+
+import (
+	"context"
+	"time"
+
+	"auth-service/internal/db"
+	"auth-service/internal/policies"
+	"auth-service/internal/utils"
+)
+
+// Size is how many recent passwords (the current one included) cannot be reused
+func Size(ctx context.Context) int {
+	return policies.Int(ctx, "password_history_count", 5)
+}
+
+// MaxAge is how long a password stays valid; 0 means passwords never expire
+func MaxAge(ctx context.Context) time.Duration {
+	return time.Duration(policies.Int(ctx, "password_max_age_days", 0)) * 24 * time.Hour
+}
+
+// Reused reports whether password matches one of the user's recent passwords.
+// Each stored hash is checked on the hashing pool, so this can fail with
+// passwordhash.ErrOverloaded.
+func Reused(ctx context.Context, userID int, password string) (bool, error) {
+	size := Size(ctx)
+	if size <= 0 {
+		return false, nil
+	}
+
+	rows, err := db.DB.Query(ctx, `
+		SELECT password_hash FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2;
+	`, userID, size)
+	if err != nil {
+		return false, err
+	}
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return false, err
+		}
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	for _, hash := range hashes {
+		match, err := utils.CheckPassword(password, hash)
+		if err != nil {
+			return false, err
+		}
+		if match {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Record stores a newly set password hash and forgets entries beyond the
+// history size (the latest one is always kept)
+func Record(ctx context.Context, userID int, hash string) error {
+	_, err := db.DB.Exec(ctx,
+		"INSERT INTO password_history (user_id, password_hash, created_at) VALUES ($1, $2, NOW());",
+		userID, hash)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.DB.Exec(ctx, `
+		DELETE FROM password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		);
+	`, userID, max(Size(ctx), 1))
+	return err
+}
+
+// Expired reports whether a password set at changedAt has outlived MaxAge
+func Expired(ctx context.Context, changedAt time.Time) bool {
+	maxAge := MaxAge(ctx)
+	return maxAge > 0 && time.Now().UTC().After(changedAt.Add(maxAge))
+}
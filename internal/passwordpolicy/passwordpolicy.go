@@ -0,0 +1,140 @@
+package passwordpolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"auth-service/internal/breach"
+	"auth-service/internal/policies"
+)
+
+// Settings are the password rules, read from auth_policies
+type Settings struct {
+	MinLength     int  // in characters
+	MaxLength     int  // in characters; 0 means no limit
+	RequireUpper  bool // at least one uppercase letter
+	RequireLower  bool // at least one lowercase letter
+	RequireDigit  bool // at least one digit
+	RequireSymbol bool // at least one character that is neither a letter nor a digit
+	ForbidEmail   bool // the password may not contain the email or its local part
+	ForbidBreach  bool // the password may not appear in the breach list (BREACHED_PASSWORDS_FILE)
+	MinStrength   int  // minimum Strength score, 0 (anything) to 4 (very strong)
+}
+
+// Violation is one failed rule, reported to clients as-is
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// LoadSettings reads the current rules; it fails rather than fall back to
+// the defaults when the policies cannot be read
+func LoadSettings(ctx context.Context) (Settings, error) {
+	r := policies.NewReader(ctx)
+	s := Settings{
+		MinLength:     r.Int("password_min_length", 8),
+		MaxLength:     r.Int("password_max_length", 64),
+		RequireUpper:  r.Bool("password_require_uppercase", false),
+		RequireLower:  r.Bool("password_require_lowercase", false),
+		RequireDigit:  r.Bool("password_require_digit", false),
+		RequireSymbol: r.Bool("password_require_symbol", false),
+		ForbidEmail:   r.Bool("password_forbid_email", true),
+		ForbidBreach:  r.Bool("password_forbid_breached", true),
+		MinStrength:   r.Int("password_min_strength", 2),
+	}
+	return s, r.Err
+}
+
+// Check validates a new password for the account with the given email
+// against the current policy. It returns no violations when the password is
+// acceptable, and an error when the policy could not be loaded.
+func Check(ctx context.Context, password, email string) ([]Violation, error) {
+	s, err := LoadSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Validate(password, email), nil
+}
+
+// Validate returns every rule the password breaks
+func (s Settings) Validate(password, email string) []Violation {
+	var violations []Violation
+	fail := func(rule, format string, args ...any) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < s.MinLength {
+		fail("min_length", "Password must be at least %d characters long", s.MinLength)
+	}
+	if s.MaxLength > 0 && length > s.MaxLength {
+		fail("max_length", "Password must be at most %d characters long", s.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+	if s.RequireUpper && !upper {
+		fail("uppercase", "Password must contain an uppercase letter")
+	}
+	if s.RequireLower && !lower {
+		fail("lowercase", "Password must contain a lowercase letter")
+	}
+	if s.RequireDigit && !digit {
+		fail("digit", "Password must contain a digit")
+	}
+	if s.RequireSymbol && !symbol {
+		fail("symbol", "Password must contain a symbol")
+	}
+
+	if s.ForbidEmail && containsEmail(password, email) {
+		fail("contains_email", "Password must not contain your email address")
+	}
+
+	if s.ForbidBreach && breach.Contains(password) {
+		fail("breached", "Password appears in a list of breached passwords")
+	}
+
+	if s.MinStrength > 0 {
+		if score, _ := Strength(password, emailParts(email)...); score < s.MinStrength {
+			fail("strength", "Password is too easy to guess (strength %d of 4, at least %d required)", score, s.MinStrength)
+		}
+	}
+
+	return violations
+}
+
+// containsEmail reports whether password contains the address or its local part
+func containsEmail(password, email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+	password = strings.ToLower(password)
+	if strings.Contains(password, email) {
+		return true
+	}
+	local, _, _ := strings.Cut(email, "@")
+	// Very short local parts ("a@...") would reject too many passwords
+	return len(local) >= 3 && strings.Contains(password, local)
+}
+
+// emailParts splits an address into the words a guesser would try first
+func emailParts(email string) []string {
+	return strings.FieldsFunc(strings.ToLower(email), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,114 @@
+package passwordpolicy
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"auth-service/internal/breach"
+)
+
+func TestStrength(t *testing.T) {
+	cases := []struct {
+		name       string
+		password   string
+		userInputs []string
+		maxScore   int // the estimate must not exceed this
+		minScore   int
+	}{
+		{"empty", "", nil, 0, 0},
+		{"dictionary word", "password", nil, 0, 0},
+		{"dictionary word, upper case", "PASSWORD", nil, 0, 0},
+		{"leet", "p@ssw0rd", nil, 0, 0},
+		{"leet, upper case", "P@SSW0RD", nil, 0, 0},
+		{"keyboard row", "qwertyuiop", nil, 0, 0},
+		{"keyboard row, reversed", "poiuytrewq", nil, 0, 0},
+		{"keyboard row and digits", "zxcvbnm123", nil, 1, 0},
+		{"ascending sequence", "abcdefghij", nil, 0, 0},
+		{"descending sequence", "987654321", nil, 0, 0},
+		{"repeated character", "aaaaaaaaaaaa", nil, 0, 0},
+		{"repeated block", "abcabcabcabc", nil, 0, 0},
+		{"year", "1987", nil, 0, 0},
+		{"repeated year", "19871987", nil, 0, 0},
+		{"word and year", "monkey2019", nil, 1, 0},
+		{"random 10 characters", "xk7Qm2vLp9", nil, 4, 3},
+		{"random 12 characters", "kT9#mQ2vLx8&", nil, 4, 4},
+		{"name without user inputs", "annsmith2020", nil, 4, 3},
+		{"email local part as user input", "annsmith2020", emailParts("annsmith@example.com"), 1, 0},
+		{"email domain as user input", "example2020", emailParts("ann@example.com"), 1, 0},
+		{"over the analysed length", strings.Repeat("a", maxAnalysedLength+1), nil, 4, 4},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			score, guesses := Strength(tc.password, tc.userInputs...)
+			if score > tc.maxScore || score < tc.minScore {
+				t.Fatalf("Strength(%q) = %d (%.3g guesses), want %d..%d", tc.password, score, guesses, tc.minScore, tc.maxScore)
+			}
+		})
+	}
+
+	// Inputs longer than maxAnalysedLength runes are not scanned at all
+	if _, guesses := Strength(strings.Repeat("é", maxAnalysedLength+1)); !math.IsInf(guesses, 1) {
+		t.Errorf("257 multi-byte runes: guesses = %v, want +Inf", guesses)
+	}
+	if _, guesses := Strength(strings.Repeat("é", maxAnalysedLength)); math.IsInf(guesses, 1) {
+		t.Error("256 multi-byte runes were not analysed")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	breach.SetChecker(breachList{"Summer2024!": true})
+	t.Cleanup(func() { breach.SetChecker(nil) })
+
+	lenient := Settings{MinLength: 1}
+	cases := []struct {
+		name     string
+		settings Settings
+		password string
+		email    string
+		want     []string
+	}{
+		{"acceptable", lenient, "anything", "", nil},
+		{"too short", Settings{MinLength: 8}, "short", "", []string{"min_length"}},
+		{"multi-byte length counts characters", Settings{MinLength: 8}, "ééééééé", "", []string{"min_length"}},
+		{"multi-byte at the minimum", Settings{MinLength: 8}, "éééééééé", "", nil},
+		{"too long", Settings{MinLength: 1, MaxLength: 4}, "abcde", "", []string{"max_length"}},
+		{"multi-byte at the maximum", Settings{MinLength: 1, MaxLength: 4}, "日本語字", "", nil},
+		{"no maximum", Settings{MinLength: 1}, strings.Repeat("x", 1000), "", nil},
+		{"uppercase", Settings{MinLength: 1, RequireUpper: true}, "lower", "", []string{"uppercase"}},
+		{"lowercase", Settings{MinLength: 1, RequireLower: true}, "UPPER", "", []string{"lowercase"}},
+		{"digit", Settings{MinLength: 1, RequireDigit: true}, "nodigits", "", []string{"digit"}},
+		{"symbol", Settings{MinLength: 1, RequireSymbol: true}, "abc123", "", []string{"symbol"}},
+		{"non-latin letters are not symbols", Settings{MinLength: 1, RequireSymbol: true}, "пароль", "", []string{"symbol"}},
+		{"all classes present", Settings{MinLength: 1, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}, "aB3$", "", nil},
+		{"contains email", Settings{MinLength: 1, ForbidEmail: true}, "x-Ann@Example.com-x", "ann@example.com", []string{"contains_email"}},
+		{"contains local part", Settings{MinLength: 1, ForbidEmail: true}, "myannsmith!", "annsmith@example.com", []string{"contains_email"}},
+		{"short local part ignored", Settings{MinLength: 1, ForbidEmail: true}, "banana", "an@example.com", nil},
+		{"email rule off", lenient, "ann@example.com", "ann@example.com", nil},
+		{"breached", Settings{MinLength: 1, ForbidBreach: true}, "Summer2024!", "", []string{"breached"}},
+		{"breach rule off", lenient, "Summer2024!", "", nil},
+		{"too weak", Settings{MinLength: 1, MinStrength: 2}, "password", "", []string{"strength"}},
+		{"weak because of the email", Settings{MinLength: 1, MinStrength: 3}, "annsmith2020", "annsmith@example.com", []string{"strength"}},
+		{"strong enough", Settings{MinLength: 1, MinStrength: 3}, "kT9#mQ2vLx8&", "", nil},
+		{"several rules at once", Settings{MinLength: 10, RequireDigit: true, MinStrength: 2}, "password", "", []string{"min_length", "digit", "strength"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, v := range tc.settings.Validate(tc.password, tc.email) {
+				if v.Message == "" {
+					t.Errorf("rule %s has no message", v.Rule)
+				}
+				got = append(got, v.Rule)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("Validate(%q) broke %v, want %v", tc.password, got, tc.want)
+			}
+		})
+	}
+}
+
+type breachList map[string]bool
+
+func (b breachList) Contains(password string) bool { return b[password] }
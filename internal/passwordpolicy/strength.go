@@ -0,0 +1,275 @@
+package passwordpolicy
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonWords are frequent passwords and words, most common first. The rank
+// of a word is the number of guesses an attacker needs to reach it.
+//
+//go:embed common_words.txt
+var commonWords string
+
+var dictionary = func() map[string]int {
+	ranks := map[string]int{}
+	for i, w := range strings.Fields(commonWords) {
+		if _, ok := ranks[w]; !ok {
+			ranks[w] = i + 1
+		}
+	}
+	return ranks
+}()
+
+// keyboardRows are walked left-to-right or right-to-left ("qwer", "lkjh")
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// leet maps common character substitutions back to letters
+var leet = map[rune]rune{'4': 'a', '@': 'a', '3': 'e', '1': 'i', '!': 'i', '0': 'o', '5': 's', '$': 's', '7': 't', '+': 't'}
+
+const (
+	maxAnalysedLength     = 256 // longer inputs are not worth the quadratic scan
+	bruteforceCardinality = 10  // guesses per unmatched character
+	minPatternGuesses     = 50  // a recognised pattern is never cheaper than this
+)
+
+// Strength estimates how hard a password is to guess, in the spirit of zxcvbn.
+// The password is split into the cheapest run of patterns an attacker would
+// try (common words, user inputs such as the email, keyboard walks, sequences,
+// repeats and years), falling back to brute force for the rest. The score is
+// 0 (trivial) to 4 (very strong), using zxcvbn's guess thresholds.
+func Strength(password string, userInputs ...string) (score int, guesses float64) {
+	runes := []rune(password)
+	n := len(runes)
+	if n == 0 {
+		return 0, 1
+	}
+	if n > maxAnalysedLength {
+		return 4, math.Inf(1)
+	}
+
+	// Words the user gave us rank ahead of everything else
+	words := make(map[string]int, len(dictionary)+len(userInputs))
+	for w, rank := range dictionary {
+		words[w] = rank + len(userInputs)
+	}
+	for i, w := range userInputs {
+		if w = strings.ToLower(w); len(w) >= 3 {
+			words[w] = i + 1
+		}
+	}
+
+	// best[i] is the cheapest way to guess the first i characters
+	type step struct {
+		guesses  float64
+		segments int
+		brute    bool // the last segment is brute force and can be extended
+	}
+	best := make([]step, n+1)
+	best[0] = step{guesses: 1}
+	for end := 1; end <= n; end++ {
+		prev := best[end-1]
+		cur := step{guesses: prev.guesses * bruteforceCardinality, segments: prev.segments, brute: true}
+		if !prev.brute {
+			cur.segments++
+		}
+
+		for start := 0; start <= end-3; start++ {
+			g := patternGuesses(runes[start:end], words)
+			if g == 0 {
+				continue
+			}
+			candidate := best[start].guesses * math.Max(g, minPatternGuesses)
+			if candidate < cur.guesses {
+				cur = step{guesses: candidate, segments: best[start].segments + 1}
+			}
+		}
+		best[end] = cur
+	}
+
+	// Guessing the order of the segments costs extra, as in zxcvbn
+	guesses = best[n].guesses * factorial(best[n].segments)
+
+	switch {
+	case guesses < 1e3+5:
+		return 0, guesses
+	case guesses < 1e6+5:
+		return 1, guesses
+	case guesses < 1e8+5:
+		return 2, guesses
+	case guesses < 1e10+5:
+		return 3, guesses
+	default:
+		return 4, guesses
+	}
+}
+
+// patternGuesses returns the guesses needed for s as a single pattern,
+// or 0 if s matches none
+func patternGuesses(s []rune, words map[string]int) float64 {
+	var best float64
+	consider := func(g float64) {
+		if g > 0 && (best == 0 || g < best) {
+			best = g
+		}
+	}
+	consider(dictionaryGuesses(s, words))
+	consider(repeatGuesses(s))
+	consider(sequenceGuesses(s))
+	consider(keyboardGuesses(s))
+	consider(yearGuesses(s))
+	return best
+}
+
+func dictionaryGuesses(s []rune, words map[string]int) float64 {
+	lower := strings.ToLower(string(s))
+	if rank, ok := words[lower]; ok {
+		return float64(rank) * caseVariations(s)
+	}
+
+	// Undo l33t substitutions ("p@ssw0rd")
+	subs := 0
+	plain := []rune(lower)
+	for i, r := range plain {
+		if l, ok := leet[r]; ok {
+			plain[i] = l
+			subs++
+		}
+	}
+	if subs == 0 {
+		return 0
+	}
+	if rank, ok := words[string(plain)]; ok {
+		return float64(rank) * caseVariations(s) * math.Pow(2, float64(subs))
+	}
+	return 0
+}
+
+// caseVariations is the extra cost of capitalisation an attacker has to guess
+func caseVariations(s []rune) float64 {
+	upper, letters := 0, 0
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	switch {
+	case upper == 0:
+		return 1
+	case upper == letters, upper == 1 && unicode.IsUpper(s[0]):
+		return 2
+	default:
+		return math.Pow(2, float64(min(upper, letters-upper)))
+	}
+}
+
+// repeatGuesses matches a unit repeated end to end ("aaaa", "abcabc")
+func repeatGuesses(s []rune) float64 {
+	for unit := 1; unit <= len(s)/2; unit++ {
+		if len(s)%unit != 0 {
+			continue
+		}
+		repeated := true
+		for i := unit; i < len(s) && repeated; i++ {
+			repeated = s[i] == s[i-unit]
+		}
+		if !repeated {
+			continue
+		}
+		count := float64(len(s) / unit)
+		if unit == 1 {
+			return float64(cardinality(s[0])) * count
+		}
+		_, g := Strength(string(s[:unit]))
+		return g * count
+	}
+	return 0
+}
+
+// sequenceGuesses matches runs such as "abcd", "9876" or "ace"
+func sequenceGuesses(s []rune) float64 {
+	delta := s[1] - s[0]
+	if delta == 0 || delta > 2 || delta < -2 {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i]-s[i-1] != delta {
+			return 0
+		}
+	}
+
+	base := float64(cardinality(s[0]))
+	if strings.ContainsRune("aAzZ019", s[0]) {
+		// Obvious starting points are tried first
+		base = 4
+	}
+	g := base * float64(len(s))
+	if delta < 0 {
+		g *= 2
+	}
+	return g
+}
+
+func keyboardGuesses(s []rune) float64 {
+	if len(s) < 4 {
+		return 0
+	}
+	lower := strings.ToLower(string(s))
+	for _, row := range keyboardRows {
+		if strings.Contains(row, lower) {
+			return 20 * float64(len(s))
+		}
+		if strings.Contains(row, reverse(lower)) {
+			return 40 * float64(len(s))
+		}
+	}
+	return 0
+}
+
+// yearGuesses matches recent years, a common suffix
+func yearGuesses(s []rune) float64 {
+	if len(s) != 4 || (string(s[:2]) != "19" && string(s[:2]) != "20") {
+		return 0
+	}
+	for _, r := range s[2:] {
+		if r < '0' || r > '9' {
+			return 0
+		}
+	}
+	return 120
+}
+
+// cardinality is the size of the character class r belongs to
+func cardinality(r rune) int {
+	switch {
+	case r >= '0' && r <= '9':
+		return 10
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		return 26
+	case r < 128:
+		return 33
+	default:
+		return 100
+	}
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+	return f
+}
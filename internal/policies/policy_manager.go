@@ -0,0 +1,199 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"auth-service/internal/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// cacheTTL bounds how long a replica may serve a policy value changed elsewhere
+const cacheTTL = 30 * time.Second
+
+type cachedValue struct {
+	value   string
+	found   bool
+	expires time.Time
+}
+
+var (
+	cacheMu sync.RWMutex
+	cache   = map[string]cachedValue{}
+)
+
+// Lookup returns the raw (JSON-encoded or plain) value of a policy. A missing
+// policy is not an error; a failed query is, and is not cached, so an outage
+// never pins every replica to the defaults for a whole TTL.
+func Lookup(ctx context.Context, name string) (string, bool, error) {
+	cacheMu.RLock()
+	entry, ok := cache[name]
+	cacheMu.RUnlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.value, entry.found, nil
+	}
+
+	var value string
+	err := db.DB.QueryRow(ctx, "SELECT value FROM auth_policies WHERE name=$1;", name).Scan(&value)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("⚠️  Failed to read policy %s: %v", name, err)
+		return "", false, err
+	}
+	found := err == nil
+
+	cacheMu.Lock()
+	cache[name] = cachedValue{value: value, found: found, expires: time.Now().Add(cacheTTL)}
+	cacheMu.Unlock()
+
+	return value, found, nil
+}
+
+// Get returns the raw value of a policy, treating a failed lookup as missing.
+// Policies that enforce security should be read through a Reader instead.
+func Get(ctx context.Context, name string) (string, bool) {
+	value, found, _ := Lookup(ctx, name)
+	return value, found
+}
+
+// Invalidate drops cached values so the next read hits the database
+func Invalidate() {
+	cacheMu.Lock()
+	cache = map[string]cachedValue{}
+	cacheMu.Unlock()
+}
+
+// Reader reads policies and keeps the first lookup error in Err, so callers
+// that enforce security can fail closed instead of falling back to defaults
+type Reader struct {
+	ctx context.Context
+	Err error
+}
+
+// NewReader returns a Reader using ctx for its lookups
+func NewReader(ctx context.Context) *Reader {
+	return &Reader{ctx: ctx}
+}
+
+func (r *Reader) get(name string) (string, bool) {
+	value, found, err := Lookup(r.ctx, name)
+	if err != nil && r.Err == nil {
+		r.Err = fmt.Errorf("policy %s: %w", name, err)
+	}
+	return value, found
+}
+
+// Decode unmarshals a JSON policy value into v
+func (r *Reader) Decode(name string, v any) bool {
+	raw, ok := r.get(name)
+	if !ok {
+		return false
+	}
+	return json.Unmarshal([]byte(raw), v) == nil
+}
+
+// String returns a policy as a string, accepting both "quoted" and bare values
+func (r *Reader) String(name, def string) string {
+	raw, ok := r.get(name)
+	if !ok {
+		return def
+	}
+	var s string
+	if json.Unmarshal([]byte(raw), &s) == nil {
+		return s
+	}
+	return strings.TrimSpace(raw)
+}
+
+// Bool returns a policy as a boolean ("true", true, "1", ...)
+func (r *Reader) Bool(name string, def bool) bool {
+	b, err := strconv.ParseBool(r.String(name, strconv.FormatBool(def)))
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// Int returns a policy as an integer
+func (r *Reader) Int(name string, def int) int {
+	n, err := strconv.Atoi(r.String(name, strconv.Itoa(def)))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// Duration returns a policy as a duration. Values are Go duration strings
+// ("15m", "168h") or a plain number of seconds.
+func (r *Reader) Duration(name string, def time.Duration) time.Duration {
+	d, ok := ParseDuration(r.String(name, ""))
+	if !ok {
+		return def
+	}
+	return d
+}
+
+// StringSlice returns a JSON array policy such as ["admin","service"]
+func (r *Reader) StringSlice(name string, def []string) []string {
+	var arr []string
+	if !r.Decode(name, &arr) {
+		return def
+	}
+	return arr
+}
+
+// The package-level helpers fall back to def when a lookup fails
+
+// Decode unmarshals a JSON policy value into v
+func Decode(ctx context.Context, name string, v any) bool {
+	return NewReader(ctx).Decode(name, v)
+}
+
+// String returns a policy as a string, accepting both "quoted" and bare values
+func String(ctx context.Context, name, def string) string {
+	return NewReader(ctx).String(name, def)
+}
+
+// Bool returns a policy as a boolean ("true", true, "1", ...)
+func Bool(ctx context.Context, name string, def bool) bool {
+	return NewReader(ctx).Bool(name, def)
+}
+
+// Int returns a policy as an integer
+func Int(ctx context.Context, name string, def int) int {
+	return NewReader(ctx).Int(name, def)
+}
+
+// Duration returns a policy as a duration. Values are Go duration strings
+// ("15m", "168h") or a plain number of seconds.
+func Duration(ctx context.Context, name string, def time.Duration) time.Duration {
+	return NewReader(ctx).Duration(name, def)
+}
+
+// StringSlice returns a JSON array policy such as ["admin","service"]
+func StringSlice(ctx context.Context, name string, def []string) []string {
+	return NewReader(ctx).StringSlice(name, def)
+}
+
+// ParseDuration parses a duration string or a number of seconds
+func ParseDuration(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(s); err == nil {
+		return time.Duration(secs) * time.Second, secs > 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
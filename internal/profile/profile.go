@@ -0,0 +1,379 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	_ "time/tzdata" // timezone validation must not depend on the host's zoneinfo
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+
+	"auth-service/internal/db"
+	"auth-service/internal/policies"
+)
+
+// Limits on profile values
+const (
+	MaxDisplayName   = 100
+	MaxAvatarURL     = 2048
+	MaxMetadataBytes = 8 << 10 // per part, JSON encoded
+	MaxMetadataKey   = 64
+)
+
+// ErrAdminOnly is returned when a user patches the admin part of their own metadata
+var ErrAdminOnly = errors.New("metadata.admin can only be changed by an administrator")
+
+// Profile is the editable part of a user account. Unset fields are null.
+type Profile struct {
+	DisplayName *string  `json:"display_name"`
+	Locale      *string  `json:"locale"`
+	Timezone    *string  `json:"timezone"`
+	AvatarURL   *string  `json:"avatar_url"`
+	Metadata    Metadata `json:"metadata"`
+}
+
+// Metadata is free-form JSON stored with the user. Users edit User; only
+// admins edit Admin, which users can still read.
+type Metadata struct {
+	User  map[string]any `json:"user"`
+	Admin map[string]any `json:"admin"`
+}
+
+// Patch is a partial update. A nil field is left alone; a field pointing at
+// nil clears the value. Metadata parts are JSON merge patches (RFC 7396):
+// a null value removes the key.
+type Patch struct {
+	DisplayName   **string
+	Locale        **string
+	Timezone      **string
+	AvatarURL     **string
+	UserMetadata  map[string]any
+	AdminMetadata map[string]any
+}
+
+// Fields lists the names of the fields a patch changes
+func (p *Patch) Fields() []string {
+	var fields []string
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"display_name", p.DisplayName != nil},
+		{"locale", p.Locale != nil},
+		{"timezone", p.Timezone != nil},
+		{"avatar_url", p.AvatarURL != nil},
+		{"metadata.user", p.UserMetadata != nil},
+		{"metadata.admin", p.AdminMetadata != nil},
+	} {
+		if f.set {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// ParsePatch decodes and validates a PATCH body such as
+// {"display_name": "Ann", "timezone": null, "metadata": {"user": {"theme": "dark"}}}
+func ParsePatch(body []byte) (*Patch, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil || raw == nil {
+		return nil, errors.New("request body must be a JSON object")
+	}
+
+	p := &Patch{}
+	for name, value := range raw {
+		var err error
+		switch name {
+		case "display_name":
+			p.DisplayName, err = parseField(value, validateDisplayName)
+		case "locale":
+			p.Locale, err = parseField(value, validateLocale)
+		case "timezone":
+			p.Timezone, err = parseField(value, validateTimezone)
+		case "avatar_url":
+			p.AvatarURL, err = parseField(value, validateAvatarURL)
+		case "metadata":
+			err = p.parseMetadata(value)
+		default:
+			err = errors.New("unknown field")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return p, nil
+}
+
+// parseField reads a nullable string; "" clears the value like null does
+func parseField(raw json.RawMessage, validate func(string) (string, error)) (**string, error) {
+	var s *string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, errors.New("must be a string or null")
+	}
+	if s != nil {
+		v := strings.TrimSpace(*s)
+		if v == "" {
+			s = nil
+		} else {
+			v, err := validate(v)
+			if err != nil {
+				return nil, err
+			}
+			s = &v
+		}
+	}
+	return &s, nil
+}
+
+func (p *Patch) parseMetadata(raw json.RawMessage) error {
+	var parts map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err != nil || parts == nil {
+		return errors.New(`must be an object with "user" and/or "admin"`)
+	}
+	for name, value := range parts {
+		var patch map[string]any
+		if err := json.Unmarshal(value, &patch); err != nil || patch == nil {
+			return fmt.Errorf("%s must be an object", name)
+		}
+		for key := range patch {
+			if key == "" || len(key) > MaxMetadataKey {
+				return fmt.Errorf("%s: keys must be 1 to %d bytes", name, MaxMetadataKey)
+			}
+		}
+		switch name {
+		case "user":
+			p.UserMetadata = patch
+		case "admin":
+			p.AdminMetadata = patch
+		default:
+			return fmt.Errorf("unknown part %q", name)
+		}
+	}
+	return nil
+}
+
+func validateDisplayName(s string) (string, error) {
+	if utf8.RuneCountInString(s) > MaxDisplayName {
+		return "", fmt.Errorf("must be at most %d characters", MaxDisplayName)
+	}
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return "", errors.New("must not contain control characters")
+		}
+	}
+	return s, nil
+}
+
+// validateLocale accepts a BCP 47 tag and returns its canonical form (en-us → en-US)
+func validateLocale(s string) (string, error) {
+	tag, err := language.Parse(s)
+	if err != nil {
+		return "", errors.New("must be a BCP 47 language tag such as en-US")
+	}
+	return tag.String(), nil
+}
+
+// validateTimezone accepts an IANA zone name such as Europe/Berlin
+func validateTimezone(s string) (string, error) {
+	if s == "Local" {
+		return "", errors.New("must be an IANA time zone such as Europe/Berlin")
+	}
+	loc, err := time.LoadLocation(s)
+	if err != nil {
+		return "", errors.New("must be an IANA time zone such as Europe/Berlin")
+	}
+	return loc.String(), nil
+}
+
+func validateAvatarURL(s string) (string, error) {
+	if len(s) > MaxAvatarURL {
+		return "", fmt.Errorf("must be at most %d bytes", MaxAvatarURL)
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil {
+		return "", errors.New("must be an absolute http(s) URL")
+	}
+	return u.String(), nil
+}
+
+// Get loads a user's profile; pgx.ErrNoRows means the user does not exist
+func Get(ctx context.Context, userID int) (*Profile, error) {
+	var p Profile
+	err := db.DB.QueryRow(ctx, `
+		SELECT display_name, locale, timezone, avatar_url, metadata
+		FROM users WHERE id = $1;
+	`, userID).Scan(&p.DisplayName, &p.Locale, &p.Timezone, &p.AvatarURL, &p.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	p.Metadata.normalize()
+	return &p, nil
+}
+
+// Update applies a patch and returns the resulting profile. The patch must
+// come from ParsePatch; callers decide who may send AdminMetadata.
+func Update(ctx context.Context, userID int, patch *Patch) (*Profile, error) {
+	tx, err := db.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var p Profile
+	err = tx.QueryRow(ctx, `
+		SELECT display_name, locale, timezone, avatar_url, metadata
+		FROM users WHERE id = $1
+		FOR UPDATE;
+	`, userID).Scan(&p.DisplayName, &p.Locale, &p.Timezone, &p.AvatarURL, &p.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	p.Metadata.normalize()
+
+	if err := p.apply(patch); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET display_name = $2, locale = $3, timezone = $4, avatar_url = $5, metadata = $6, updated_at = NOW()
+		WHERE id = $1;
+	`, userID, p.DisplayName, p.Locale, p.Timezone, p.AvatarURL, p.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// apply merges a patch into the profile and checks the metadata size limits
+func (p *Profile) apply(patch *Patch) error {
+	for _, f := range []struct {
+		dst **string
+		src **string
+	}{
+		{&p.DisplayName, patch.DisplayName},
+		{&p.Locale, patch.Locale},
+		{&p.Timezone, patch.Timezone},
+		{&p.AvatarURL, patch.AvatarURL},
+	} {
+		if f.src != nil {
+			*f.dst = *f.src
+		}
+	}
+
+	p.Metadata.User = mergePatch(p.Metadata.User, patch.UserMetadata)
+	p.Metadata.Admin = mergePatch(p.Metadata.Admin, patch.AdminMetadata)
+	for name, part := range map[string]map[string]any{"user": p.Metadata.User, "admin": p.Metadata.Admin} {
+		b, err := json.Marshal(part)
+		if err != nil {
+			return err
+		}
+		if len(b) > MaxMetadataBytes {
+			return &ValidationError{Field: "metadata." + name, Message: fmt.Sprintf("must encode to at most %d bytes", MaxMetadataBytes)}
+		}
+	}
+	return nil
+}
+
+// ValidationError reports a patch that is well-formed but not acceptable
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string { return e.Field + ": " + e.Message }
+
+// mergePatch applies an RFC 7396 merge patch to an object
+func mergePatch(target, patch map[string]any) map[string]any {
+	if target == nil {
+		target = map[string]any{}
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		if sub, ok := value.(map[string]any); ok {
+			existing, _ := target[key].(map[string]any)
+			target[key] = mergePatch(existing, sub)
+			continue
+		}
+		target[key] = value
+	}
+	return target
+}
+
+func (m *Metadata) normalize() {
+	if m.User == nil {
+		m.User = map[string]any{}
+	}
+	if m.Admin == nil {
+		m.Admin = map[string]any{}
+	}
+}
+
+// ClaimKeys returns the jwt_metadata_claims policy: metadata keys copied
+// into access tokens, written as "user.<key>" or "admin.<key>"
+func ClaimKeys(ctx context.Context) []string {
+	return policies.StringSlice(ctx, "jwt_metadata_claims", nil)
+}
+
+// Claims returns the metadata claim for a user's access tokens, such as
+// {"admin": {"tenant": "acme"}}, or nil when nothing is projected. Values
+// from the user part are self-asserted; services must not trust them for
+// authorization.
+func Claims(ctx context.Context, userID int) (map[string]any, error) {
+	keys := ClaimKeys(ctx)
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	var m Metadata
+	if err := db.DB.QueryRow(ctx, "SELECT metadata FROM users WHERE id = $1;", userID).Scan(&m); err != nil {
+		return nil, err
+	}
+	return m.project(keys), nil
+}
+
+// project picks the listed keys; unknown parts and missing keys are skipped
+func (m Metadata) project(keys []string) map[string]any {
+	claims := map[string]any{}
+	for _, k := range keys {
+		part, key, ok := strings.Cut(k, ".")
+		if !ok {
+			continue
+		}
+		var src map[string]any
+		switch part {
+		case "user":
+			src = m.User
+		case "admin":
+			src = m.Admin
+		default:
+			continue
+		}
+		value, ok := src[key]
+		if !ok {
+			continue
+		}
+		dst, _ := claims[part].(map[string]any)
+		if dst == nil {
+			dst = map[string]any{}
+			claims[part] = dst
+		}
+		dst[key] = value
+	}
+	if len(claims) == 0 {
+		return nil
+	}
+	return claims
+}
@@ -0,0 +1,110 @@
+package profile
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePatch(t *testing.T) {
+	p, err := ParsePatch([]byte(`{
+		"display_name": "  Ann  ",
+		"locale": "en-us",
+		"timezone": null,
+		"avatar_url": "",
+		"metadata": {"user": {"theme": "dark"}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.DisplayName == nil || **p.DisplayName != "Ann" {
+		t.Errorf("display_name not trimmed")
+	}
+	if p.Locale == nil || **p.Locale != "en-US" {
+		t.Errorf("locale not canonicalized")
+	}
+	if p.Timezone == nil || *p.Timezone != nil || p.AvatarURL == nil || *p.AvatarURL != nil {
+		t.Errorf("null and empty string should clear the field")
+	}
+	if p.AdminMetadata != nil || p.UserMetadata["theme"] != "dark" {
+		t.Errorf("metadata = user %v admin %v", p.UserMetadata, p.AdminMetadata)
+	}
+	want := []string{"display_name", "locale", "timezone", "avatar_url", "metadata.user"}
+	if got := p.Fields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields() = %v, want %v", got, want)
+	}
+
+	for _, body := range []string{
+		`[]`,
+		`{"email": "x@example.com"}`,
+		`{"display_name": 7}`,
+		`{"display_name": "` + strings.Repeat("a", MaxDisplayName+1) + `"}`,
+		`{"display_name": "a\u0007b"}`,
+		`{"locale": "not a locale"}`,
+		`{"timezone": "Mars/Olympus"}`,
+		`{"timezone": "Local"}`,
+		`{"avatar_url": "javascript:alert(1)"}`,
+		`{"avatar_url": "https://user:pw@example.com/a.png"}`,
+		`{"metadata": {"other": {}}}`,
+		`{"metadata": {"user": []}}`,
+		`{"metadata": {"user": {"": 1}}}`,
+	} {
+		if _, err := ParsePatch([]byte(body)); err == nil {
+			t.Errorf("ParsePatch(%s) succeeded, want an error", body)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	name := "Old"
+	p := Profile{
+		DisplayName: &name,
+		Metadata: Metadata{
+			User:  map[string]any{"theme": "light", "ui": map[string]any{"dense": true, "lang": "en"}},
+			Admin: map[string]any{"tenant": "acme"},
+		},
+	}
+	patch, err := ParsePatch([]byte(`{"metadata": {"user": {"theme": null, "ui": {"lang": "de"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.apply(patch); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.DisplayName == nil || *p.DisplayName != "Old" {
+		t.Errorf("omitted field changed")
+	}
+	wantUser := map[string]any{"ui": map[string]any{"dense": true, "lang": "de"}}
+	if !reflect.DeepEqual(p.Metadata.User, wantUser) {
+		t.Errorf("user metadata = %v, want %v", p.Metadata.User, wantUser)
+	}
+	if p.Metadata.Admin["tenant"] != "acme" {
+		t.Errorf("admin metadata changed: %v", p.Metadata.Admin)
+	}
+
+	big := &Patch{UserMetadata: map[string]any{"blob": strings.Repeat("x", MaxMetadataBytes)}}
+	var invalid *ValidationError
+	if err := p.apply(big); !errors.As(err, &invalid) || invalid.Field != "metadata.user" {
+		t.Errorf("oversized metadata: err = %v", err)
+	}
+}
+
+func TestProject(t *testing.T) {
+	m := Metadata{
+		User:  map[string]any{"theme": "dark", "secret": "x"},
+		Admin: map[string]any{"tenant": "acme", "plan": "pro"},
+	}
+	got := m.project([]string{"admin.tenant", "user.theme", "user.missing", "other.key", "malformed"})
+	want := map[string]any{
+		"admin": map[string]any{"tenant": "acme"},
+		"user":  map[string]any{"theme": "dark"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("project = %v, want %v", got, want)
+	}
+	if got := m.project([]string{"user.missing"}); got != nil {
+		t.Errorf("project with no matches = %v, want nil", got)
+	}
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps state in process. Limits are per replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	state   State
+	expires time.Time
+}
+
+// NewMemoryStore starts an in-process store with a background sweeper
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{entries: map[string]*memoryEntry{}}
+	go s.sweep(time.Minute)
+	return s
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(_ context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &memoryEntry{}
+		s.entries[key] = e
+	}
+	res := Apply(rule, &e.state, now)
+	e.expires = now.Add(2 * rule.Window)
+	return res, nil
+}
+
+func (s *MemoryStore) sweep(interval time.Duration) {
+	for range time.Tick(interval) {
+		now := time.Now().UTC()
+		s.mu.Lock()
+		for key, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore keeps state in the rate_limits table so every replica
+// enforces the same limits
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStore uses pool and prunes idle keys in the background
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	s := &PostgresStore{pool: pool}
+	go s.prune(5 * time.Minute)
+	return s
+}
+
+// Take implements Store. The key's row is locked for the read-modify-write.
+func (s *PostgresStore) Take(ctx context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO rate_limits (key, expires_at) VALUES ($1, $2)
+		ON CONFLICT (key) DO NOTHING;
+	`, key, now.Add(2*rule.Window))
+	if err != nil {
+		return Result{}, err
+	}
+
+	var st State
+	var updatedAt, windowStart *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT tokens, updated_at, window_start, prev_count, curr_count
+		FROM rate_limits WHERE key = $1
+		FOR UPDATE;
+	`, key).Scan(&st.Tokens, &updatedAt, &windowStart, &st.Prev, &st.Curr)
+	if err != nil {
+		return Result{}, err
+	}
+	if updatedAt != nil {
+		st.UpdatedAt = *updatedAt
+	}
+	if windowStart != nil {
+		st.WindowStart = *windowStart
+	}
+
+	res := Apply(rule, &st, now)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE rate_limits
+		SET tokens = $2, updated_at = $3, window_start = $4, prev_count = $5, curr_count = $6, expires_at = $7
+		WHERE key = $1;
+	`, key, st.Tokens, st.UpdatedAt, st.WindowStart, st.Prev, st.Curr, now.Add(2*rule.Window))
+	if err != nil {
+		return Result{}, err
+	}
+
+	return res, tx.Commit(ctx)
+}
+
+func (s *PostgresStore) prune(interval time.Duration) {
+	for range time.Tick(interval) {
+		_, err := s.pool.Exec(context.Background(), "DELETE FROM rate_limits WHERE expires_at < $1;", time.Now().UTC())
+		if err != nil {
+			log.Printf("⚠️  Failed to prune rate limits: %v", err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"auth-service/internal/policies"
+)
+
+// Algorithm selects how a rule counts requests
+type Algorithm string
+
+const (
+	// TokenBucket allows bursts up to Limit, refilling Limit tokens per Window
+	TokenBucket Algorithm = "token_bucket"
+	// SlidingWindow allows Limit requests in any trailing Window (weighted
+	// across the previous and current fixed window)
+	SlidingWindow Algorithm = "sliding_window"
+)
+
+// Rule is one named limit. Name prefixes every key so rules never share counters.
+type Rule struct {
+	Name      string        `json:"-"`
+	Algorithm Algorithm     `json:"algorithm"`
+	Limit     int           `json:"limit"`
+	Window    time.Duration `json:"-"`
+}
+
+// Result is the outcome of taking one request from a rule
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the limit is fully available again
+	RetryAfter time.Duration // until the next request would be allowed (0 if allowed)
+}
+
+// State is what a store persists per key; both algorithms share it
+type State struct {
+	Tokens      float64
+	UpdatedAt   time.Time
+	WindowStart time.Time
+	Prev, Curr  int
+}
+
+// Store keeps per-key state. Take must apply the rule atomically per key.
+type Store interface {
+	Take(ctx context.Context, key string, rule Rule, now time.Time) (Result, error)
+}
+
+var (
+	storeMu sync.RWMutex
+	store   Store = NewMemoryStore()
+)
+
+// SetStore replaces the store used by Take (e.g. with a PostgresStore on startup)
+func SetStore(s Store) {
+	storeMu.Lock()
+	store = s
+	storeMu.Unlock()
+}
+
+// Take counts one request for key under rule in the configured store
+func Take(ctx context.Context, rule Rule, key string) (Result, error) {
+	storeMu.RLock()
+	s := store
+	storeMu.RUnlock()
+	return s.Take(ctx, rule.Name+":"+key, rule, time.Now().UTC())
+}
+
+// Resolve applies overrides from the rate_limits policy, e.g.
+// {"login-ip": {"algorithm": "sliding_window", "limit": 20, "window": "1m"}}.
+// A limit of 0 disables the rule.
+func Resolve(ctx context.Context, rule Rule) (Rule, bool) {
+	var overrides map[string]struct {
+		Algorithm Algorithm `json:"algorithm"`
+		Limit     *int      `json:"limit"`
+		Window    string    `json:"window"`
+	}
+	if policies.Decode(ctx, "rate_limits", &overrides) {
+		if o, ok := overrides[rule.Name]; ok {
+			if o.Algorithm == TokenBucket || o.Algorithm == SlidingWindow {
+				rule.Algorithm = o.Algorithm
+			}
+			if o.Limit != nil {
+				rule.Limit = *o.Limit
+			}
+			if d, ok := policies.ParseDuration(strings.TrimSpace(o.Window)); ok && d > 0 {
+				rule.Window = d
+			}
+		}
+	}
+	return rule, rule.Limit > 0 && rule.Window > 0
+}
+
+// Apply advances st to now and tries to take one request. Stores call it
+// while holding whatever lock makes the key's update atomic.
+func Apply(rule Rule, st *State, now time.Time) Result {
+	if rule.Algorithm == SlidingWindow {
+		return applySlidingWindow(rule, st, now)
+	}
+	return applyTokenBucket(rule, st, now)
+}
+
+func applyTokenBucket(rule Rule, st *State, now time.Time) Result {
+	limit := float64(rule.Limit)
+	rate := limit / rule.Window.Seconds() // tokens per second
+
+	if st.UpdatedAt.IsZero() {
+		st.Tokens = limit
+	} else if elapsed := now.Sub(st.UpdatedAt).Seconds(); elapsed > 0 {
+		st.Tokens = math.Min(limit, st.Tokens+elapsed*rate)
+	}
+	st.UpdatedAt = now
+
+	res := Result{Limit: rule.Limit}
+	if st.Tokens >= 1 {
+		st.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - st.Tokens) / rate)
+	}
+	res.Remaining = int(st.Tokens)
+	res.Reset = seconds((limit - st.Tokens) / rate)
+	return res
+}
+
+func applySlidingWindow(rule Rule, st *State, now time.Time) Result {
+	window := rule.Window
+	start := now.Truncate(window)
+
+	switch {
+	case st.WindowStart.IsZero() || start.Sub(st.WindowStart) >= 2*window:
+		st.Prev, st.Curr = 0, 0
+	case start.After(st.WindowStart):
+		st.Prev, st.Curr = st.Curr, 0
+	}
+	st.WindowStart = start
+	st.UpdatedAt = now
+
+	// Weight the previous window by how much of it still overlaps
+	weight := 1 - float64(now.Sub(start))/float64(window)
+	count := float64(st.Prev)*weight + float64(st.Curr)
+
+	res := Result{Limit: rule.Limit, Reset: start.Add(window).Sub(now)}
+	if count+1 <= float64(rule.Limit) {
+		st.Curr++
+		count++
+		res.Allowed = true
+	} else if st.Prev > 0 {
+		// Wait until enough of the previous window has slid out
+		need := (count + 1 - float64(rule.Limit)) / float64(st.Prev)
+		res.RetryAfter = time.Duration(need * float64(window))
+		if res.RetryAfter > res.Reset {
+			res.RetryAfter = res.Reset
+		}
+	} else {
+		res.RetryAfter = res.Reset
+	}
+	res.Remaining = max(0, rule.Limit-int(math.Ceil(count)))
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
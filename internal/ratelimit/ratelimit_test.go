@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// take runs n requests at t and returns how many were allowed plus the last result
+func take(rule Rule, st *State, t time.Time, n int) (int, Result) {
+	allowed := 0
+	var res Result
+	for range n {
+		res = Apply(rule, st, t)
+		if res.Allowed {
+			allowed++
+		}
+	}
+	return allowed, res
+}
+
+func TestTokenBucket(t *testing.T) {
+	rule := Rule{Name: "t", Algorithm: TokenBucket, Limit: 10, Window: 10 * time.Second} // 1 token/s
+	var st State
+
+	// A full bucket allows a burst of Limit
+	allowed, res := take(rule, &st, epoch, 12)
+	if allowed != 10 {
+		t.Fatalf("burst allowed %d, want 10", allowed)
+	}
+	if res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after burst: %+v", res)
+	}
+	if res.RetryAfter != time.Second {
+		t.Fatalf("RetryAfter = %v, want 1s", res.RetryAfter)
+	}
+	if res.Reset != 10*time.Second {
+		t.Fatalf("Reset = %v, want 10s", res.Reset)
+	}
+
+	// Tokens refill at Limit per Window
+	if allowed, _ := take(rule, &st, epoch.Add(3500*time.Millisecond), 5); allowed != 3 {
+		t.Fatalf("after 3.5s allowed %d, want 3", allowed)
+	}
+
+	// ...but never beyond Limit
+	if allowed, _ := take(rule, &st, epoch.Add(time.Hour), 20); allowed != 10 {
+		t.Fatalf("after an hour allowed %d, want 10", allowed)
+	}
+
+	// A clock going backwards does not mint tokens
+	if allowed, _ := take(rule, &st, epoch, 1); allowed != 0 {
+		t.Fatal("request allowed with an empty bucket and an earlier clock")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	rule := Rule{Name: "s", Algorithm: SlidingWindow, Limit: 10, Window: time.Minute}
+	var st State
+
+	allowed, res := take(rule, &st, epoch.Add(50*time.Second), 12)
+	if allowed != 10 {
+		t.Fatalf("first window allowed %d, want 10", allowed)
+	}
+	if res.Allowed || res.Remaining != 0 || res.RetryAfter != 10*time.Second || res.Reset != 10*time.Second {
+		t.Fatalf("over the limit: %+v", res)
+	}
+
+	// 15s into the next window, the previous one still weighs 10 * 45/60 = 7.5
+	allowed, res = take(rule, &st, epoch.Add(75*time.Second), 5)
+	if allowed != 2 {
+		t.Fatalf("next window allowed %d, want 2", allowed)
+	}
+	// One more needs (7.5 + 2 + 1 - 10) / 10 of a window to slide out
+	if res.RetryAfter != 3*time.Second {
+		t.Fatalf("RetryAfter = %v, want 3s", res.RetryAfter)
+	}
+
+	// Two windows later everything is forgotten
+	if allowed, _ := take(rule, &st, epoch.Add(3*time.Minute), 10); allowed != 10 {
+		t.Fatalf("after two idle windows allowed %d, want 10", allowed)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := &MemoryStore{entries: map[string]*memoryEntry{}}
+	rule := Rule{Name: "m", Algorithm: SlidingWindow, Limit: 2, Window: time.Minute}
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		res, err := store.Take(ctx, "a", rule, epoch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != want {
+			t.Fatalf("request %d for a: allowed = %v", i, res.Allowed)
+		}
+	}
+
+	// Keys are counted independently
+	if res, _ := store.Take(ctx, "b", rule, epoch); !res.Allowed {
+		t.Fatal("key b limited by key a")
+	}
+
+	// Entries expire two windows after their last use
+	if e := store.entries["a"]; !e.expires.Equal(epoch.Add(2 * time.Minute)) {
+		t.Fatalf("expires = %v", e.expires)
+	}
+}
@@ -0,0 +1,108 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"auth-service/internal/db"
+	jwtpkg "auth-service/pkg/jwt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RevokeToken blacklists a single access token until it would have expired anyway
+func RevokeToken(ctx context.Context, claims *jwtpkg.CustomClaims) error {
+	if claims.ID == "" {
+		return errors.New("token has no jti")
+	}
+
+	// expires_at has no time zone; like every other timestamp it holds UTC
+	expiresAt := time.Now().UTC().Add(time.Hour)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time.UTC()
+	}
+
+	// Expired entries are useless, prune them while we are here
+	if _, err := db.DB.Exec(ctx, "DELETE FROM revoked_tokens WHERE expires_at < NOW();"); err != nil {
+		return err
+	}
+
+	_, err := db.DB.Exec(ctx, `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING;
+	`, claims.ID, claims.UserID, expiresAt)
+	return err
+}
+
+// RevokeSession invalidates every refresh token in a session's family
+func RevokeSession(ctx context.Context, sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	_, err := db.DB.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW()
+		WHERE family_id = $1 AND revoked = FALSE;
+	`, sessionID)
+	return err
+}
+
+// RevokeAllForUser invalidates every access and refresh token issued to a user so far.
+// iat has whole-second precision, so the cutoff is the start of the current
+// second and tokens issued within that second stay valid: a login right after
+// a password reset must not be rejected. Tokens tied to a session are cut off
+// exactly anyway, since their refresh tokens are revoked here too.
+func RevokeAllForUser(ctx context.Context, userID int) error {
+	_, err := db.DB.Exec(ctx, "UPDATE users SET tokens_valid_after = date_trunc('second', NOW()) WHERE id=$1;", userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.DB.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW()
+		WHERE user_id = $1 AND revoked = FALSE;
+	`, userID)
+	return err
+}
+
+// RevokeOtherSessions invalidates every session of a user except keepSessionID.
+// Access tokens of those sessions stop working once their refresh tokens are revoked.
+func RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) error {
+	_, err := db.DB.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW()
+		WHERE user_id = $1 AND family_id IS DISTINCT FROM $2 AND revoked = FALSE;
+	`, userID, keepSessionID)
+	return err
+}
+
+// IsRevoked reports whether a validated token has since been revoked,
+// either individually, by a logout-everywhere, because its session was ended,
+// or because the user is gone.
+func IsRevoked(ctx context.Context, claims *jwtpkg.CustomClaims) (bool, error) {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+
+	var revoked bool
+	err := db.DB.QueryRow(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+			OR COALESCE(u.tokens_valid_after > to_timestamp($3)::timestamp, FALSE)
+			OR ($4 <> '' AND NOT EXISTS (
+				SELECT 1 FROM refresh_tokens WHERE family_id = $4 AND revoked = FALSE
+			))
+		FROM users u
+		WHERE u.id = $2;
+	`, claims.ID, claims.UserID, issuedAt.Unix(), claims.SessionID).Scan(&revoked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Deleted users keep no valid tokens
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,173 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"auth-service/internal/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrRoleNotFound is returned when a role name does not exist
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrCycle is returned when a parent would make a role inherit from itself
+	ErrCycle = errors.New("role hierarchy cycle")
+)
+
+// Querier is satisfied by both the pool and a transaction
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// effectiveRolesCTE expands a user's directly assigned roles with every role
+// they inherit from through role_parents. UNION makes it terminate even if a
+// cycle slipped into the table.
+const effectiveRolesCTE = `
+	WITH RECURSIVE effective(role_id) AS (
+		SELECT role_id FROM user_roles WHERE user_id = $1
+		UNION
+		SELECT rp.parent_id FROM role_parents rp
+		JOIN effective e ON rp.role_id = e.role_id
+	)`
+
+// permissionPattern accepts "resource:action" names; "*" and "resource:*"
+// are wildcards that grant every permission / every action on a resource
+var permissionPattern = regexp.MustCompile(`^(\*|[a-z0-9_.-]+:(\*|[a-z0-9_.-]+))$`)
+
+// ValidPermissionName reports whether name is a well-formed permission
+func ValidPermissionName(name string) bool {
+	return permissionPattern.MatchString(name)
+}
+
+// EffectiveRoles returns the names of a user's assigned and inherited roles
+func EffectiveRoles(ctx context.Context, q Querier, userID int) ([]string, error) {
+	return queryNames(ctx, q, effectiveRolesCTE+`
+		SELECT r.name FROM roles r
+		JOIN effective e ON e.role_id = r.id
+		ORDER BY r.name;
+	`, userID)
+}
+
+// UserPermissions returns every permission granted to a user through their
+// assigned and inherited roles
+func UserPermissions(ctx context.Context, userID int) ([]string, error) {
+	return queryNames(ctx, db.DB, effectiveRolesCTE+`
+		SELECT DISTINCT p.name
+		FROM permissions p
+		JOIN role_permissions rp ON rp.permission_id = p.id
+		JOIN effective e ON e.role_id = rp.role_id
+		ORDER BY p.name;
+	`, userID)
+}
+
+// Parents returns the roles a role directly inherits from
+func Parents(ctx context.Context, role string) ([]string, error) {
+	return queryNames(ctx, db.DB, `
+		SELECT p.name FROM role_parents rp
+		JOIN roles r ON r.id = rp.role_id
+		JOIN roles p ON p.id = rp.parent_id
+		WHERE r.name = $1
+		ORDER BY p.name;
+	`, role)
+}
+
+// AddParent makes role inherit every permission of parent, refusing any
+// parent that already (transitively) inherits from role
+func AddParent(ctx context.Context, role, parent string) error {
+	tx, err := db.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize hierarchy changes so two concurrent edits cannot form a cycle
+	if _, err := tx.Exec(ctx, "LOCK TABLE role_parents IN SHARE ROW EXCLUSIVE MODE;"); err != nil {
+		return err
+	}
+
+	var roleID, parentID int
+	if err := tx.QueryRow(ctx, "SELECT id FROM roles WHERE name=$1;", role).Scan(&roleID); err != nil {
+		return ErrRoleNotFound
+	}
+	if err := tx.QueryRow(ctx, "SELECT id FROM roles WHERE name=$1;", parent).Scan(&parentID); err != nil {
+		return ErrRoleNotFound
+	}
+	if roleID == parentID {
+		return ErrCycle
+	}
+
+	var cycle bool
+	err = tx.QueryRow(ctx, `
+		WITH RECURSIVE ancestors(id) AS (
+			SELECT parent_id FROM role_parents WHERE role_id = $1
+			UNION
+			SELECT rp.parent_id FROM role_parents rp
+			JOIN ancestors a ON rp.role_id = a.id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2);
+	`, parentID, roleID).Scan(&cycle)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return ErrCycle
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO role_parents (role_id, parent_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;
+	`, roleID, parentID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RemoveParent stops role inheriting from parent; it reports whether a link existed
+func RemoveParent(ctx context.Context, role, parent string) (bool, error) {
+	tag, err := db.DB.Exec(ctx, `
+		DELETE FROM role_parents rp
+		USING roles r, roles p
+		WHERE rp.role_id = r.id AND rp.parent_id = p.id
+		  AND r.name = $1 AND p.name = $2;
+	`, role, parent)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func queryNames(ctx context.Context, q Querier, sql string, args ...any) ([]string, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// HasPermission reports whether granted covers required, honouring wildcards
+func HasPermission(granted []string, required string) bool {
+	resource, _, _ := strings.Cut(required, ":")
+	for _, p := range granted {
+		if p == required || p == "*" || p == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
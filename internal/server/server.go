@@ -0,0 +1,128 @@
+package server
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"auth-service/internal/config"
+	"auth-service/internal/db"
+	"auth-service/internal/handlers"
+	"auth-service/internal/middleware"
+	"auth-service/internal/passwordhash"
+	"auth-service/internal/ratelimit"
+)
+
+// NewApp builds the Fiber app with every route registered. The database and
+// signing keys must already be initialized.
+func NewApp() *fiber.App {
+	// Behind a reverse proxy, PROXY_HEADER (e.g. X-Forwarded-For) supplies the
+	// client IP used for login lockouts
+	app := fiber.New(fiber.Config{
+		ProxyHeader: config.Env("PROXY_HEADER", ""),
+	})
+
+	app.Get("api/v1/health", func(c *fiber.Ctx) error {
+		dbStatus := "disconnected"
+		if db.CheckHealth() {
+			dbStatus = "connected"
+		}
+
+		return c.JSON(fiber.Map{
+			"status":    "ok",
+			"db":        dbStatus,
+			"hash_pool": passwordhash.DefaultPool().Stats(),
+		})
+	})
+
+	app.Get("api/v1/version", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"service": "auth-service",
+			"version": "v0.1.0",
+		})
+	})
+	app.Get("/.well-known/jwks.json", handlers.GetJWKS)
+
+	// Rate limits; each can be tuned or disabled by name via the rate_limits policy
+	limit := middleware.RateLimit
+	loginIP := limit(ratelimit.Rule{Name: "login-ip", Algorithm: ratelimit.SlidingWindow, Limit: 20, Window: time.Minute}, middleware.ByIP)
+	loginEmail := limit(ratelimit.Rule{Name: "login-email", Algorithm: ratelimit.TokenBucket, Limit: 10, Window: 10 * time.Minute}, middleware.ByBodyField("email"))
+	registerIP := limit(ratelimit.Rule{Name: "register-ip", Algorithm: ratelimit.SlidingWindow, Limit: 10, Window: time.Hour}, middleware.ByIP)
+	registerEmail := limit(ratelimit.Rule{Name: "register-email", Algorithm: ratelimit.SlidingWindow, Limit: 3, Window: time.Hour}, middleware.ByBodyField("email"))
+	resendEmail := limit(ratelimit.Rule{Name: "resend-verification-email", Algorithm: ratelimit.SlidingWindow, Limit: 3, Window: time.Hour}, middleware.ByBodyField("email"))
+	resetEmail := limit(ratelimit.Rule{Name: "forgot-password-email", Algorithm: ratelimit.SlidingWindow, Limit: 3, Window: time.Hour}, middleware.ByBodyField("email"))
+	changePassword := limit(ratelimit.Rule{Name: "change-password-subject", Algorithm: ratelimit.SlidingWindow, Limit: 5, Window: 15 * time.Minute}, middleware.BySubject)
+	adminLimit := limit(ratelimit.Rule{Name: "admin-subject", Algorithm: ratelimit.TokenBucket, Limit: 120, Window: time.Minute}, middleware.BySubject)
+
+	app.Post("/api/v1/login", loginIP, loginEmail, handlers.Login)
+	app.Post("/api/v1/login/mfa", loginIP, handlers.LoginMFA)
+	app.Post("/api/v1/login/mfa/enroll", loginIP, handlers.LoginMFAEnroll)
+	app.Post("/api/v1/login/mfa/enroll/confirm", loginIP, handlers.LoginMFAEnrollConfirm)
+	app.Post("/api/v1/login/mfa/webauthn/begin", loginIP, handlers.BeginMFAPasskey)
+	app.Post("/api/v1/login/mfa/webauthn/finish", loginIP, handlers.FinishMFAPasskey)
+	app.Post("/api/v1/login/passkey/begin", loginIP, handlers.BeginPasskeyLogin)
+	app.Post("/api/v1/login/passkey/finish", loginIP, handlers.FinishPasskeyLogin)
+	app.Post("/api/v1/refresh", handlers.Refresh)
+	app.Get("/api/v1/me", middleware.AuthRequired(), handlers.Me)
+	app.Patch("/api/v1/me", middleware.AuthRequired(), handlers.UpdateMe)
+	app.Post("/api/v1/me/password", middleware.PasswordChangeAuth(), changePassword, handlers.ChangePassword)
+	app.Post("/api/v1/register", registerIP, registerEmail, handlers.Register)
+	app.Get("/api/v1/verify-email", handlers.VerifyEmail)
+	app.Post("/api/v1/verify-email", handlers.VerifyEmail)
+	app.Post("/api/v1/resend-verification", registerIP, resendEmail, handlers.ResendVerification)
+	app.Post("/api/v1/forgot-password", registerIP, resetEmail, handlers.ForgotPassword)
+	app.Post("/api/v1/reset-password", loginIP, handlers.ResetPassword)
+	app.Post("/api/v1/logout", middleware.AuthRequired(), handlers.Logout)
+	app.Post("/api/v1/logout-all", middleware.AuthRequired(), handlers.LogoutAll)
+	app.Post("/api/v1/introspect", middleware.AuthRequired(), handlers.Introspect)
+	app.Post("/api/v1/authorize", middleware.AuthRequired(), handlers.Authorize)
+	app.Post("/api/v1/authorize/batch", middleware.AuthRequired(), handlers.AuthorizeBatch)
+	app.Get("/api/v1/mfa", middleware.AuthRequired(), handlers.GetMFAStatus)
+	app.Post("/api/v1/mfa/totp/enroll", middleware.AuthRequired(), handlers.EnrollTOTP)
+	app.Post("/api/v1/mfa/totp/confirm", middleware.AuthRequired(), handlers.ConfirmTOTP)
+	app.Delete("/api/v1/mfa/totp", middleware.AuthRequired(), handlers.DisableTOTP)
+	app.Post("/api/v1/mfa/recovery-codes", middleware.AuthRequired(), handlers.RegenerateRecoveryCodes)
+	app.Post("/api/v1/webauthn/register/begin", middleware.AuthRequired(), handlers.BeginPasskeyRegistration)
+	app.Post("/api/v1/webauthn/register/finish", middleware.AuthRequired(), handlers.FinishPasskeyRegistration)
+	app.Get("/api/v1/webauthn/credentials", middleware.AuthRequired(), handlers.ListPasskeys)
+	app.Post("/api/v1/webauthn/reauth/begin", middleware.AuthRequired(), handlers.BeginPasskeyReauth)
+	app.Delete("/api/v1/webauthn/credentials/:id", middleware.AuthRequired(), handlers.DeletePasskey)
+
+	auth := middleware.AuthRequired()
+	can := middleware.RequirePermission
+
+	app.Get("/api/v1/superadmin/policies", auth, adminLimit, can("policies:read"), handlers.GetAllPolicies)
+	app.Get("/api/v1/superadmin/policies/:name", auth, adminLimit, can("policies:read"), handlers.GetPolicyByName)
+	app.Post("/api/v1/superadmin/policies", auth, adminLimit, can("policies:write"), handlers.UpsertPolicies)
+
+	app.Get("/api/v1/superadmin/keys", auth, adminLimit, can("keys:manage"), handlers.ListSigningKeys)
+	app.Post("/api/v1/superadmin/keys", auth, adminLimit, can("keys:manage"), handlers.CreateSigningKey)
+	app.Post("/api/v1/superadmin/keys/:kid/promote", auth, adminLimit, can("keys:manage"), handlers.PromoteSigningKey)
+	app.Post("/api/v1/superadmin/keys/:kid/retire", auth, adminLimit, can("keys:manage"), handlers.RetireSigningKey)
+
+	app.Get("/api/v1/admin/roles", auth, adminLimit, can("roles:read"), handlers.GetRoles)
+	app.Post("/api/v1/admin/roles", auth, adminLimit, can("roles:write"), handlers.CreateRole)
+	app.Post("/api/v1/admin/roles/:name/parents", auth, adminLimit, can("roles:write"), handlers.AddRoleParent)
+	app.Delete("/api/v1/admin/roles/:name/parents/:parent", auth, adminLimit, can("roles:write"), handlers.RemoveRoleParent)
+	app.Post("/api/v1/admin/assign-role", auth, adminLimit, can("roles:assign"), handlers.AssignRole)
+	app.Delete("/api/v1/admin/revoke-role", auth, adminLimit, can("roles:assign"), handlers.RevokeRole)
+
+	app.Get("/api/v1/admin/permissions", auth, adminLimit, can("permissions:read"), handlers.GetPermissions)
+	app.Post("/api/v1/admin/permissions", auth, adminLimit, can("permissions:write"), handlers.CreatePermission)
+	app.Delete("/api/v1/admin/permissions/:name", auth, adminLimit, can("permissions:write"), handlers.DeletePermission)
+	app.Get("/api/v1/admin/roles/:name/permissions", auth, adminLimit, can("permissions:read"), handlers.GetRolePermissions)
+	app.Post("/api/v1/admin/roles/:name/permissions", auth, adminLimit, can("permissions:write"), handlers.GrantRolePermission)
+	app.Delete("/api/v1/admin/roles/:name/permissions/:permission", auth, adminLimit, can("permissions:write"), handlers.RevokeRolePermission)
+
+	app.Get("/api/v1/admin/users", auth, adminLimit, can("users:read"), handlers.ListUsers)
+	app.Get("/api/v1/admin/users/:id", auth, adminLimit, can("users:read"), handlers.GetUserByID)
+	app.Patch("/api/v1/admin/users/:id", auth, adminLimit, can("users:write"), handlers.UpdateUserProfile)
+	app.Patch("/api/v1/admin/users/:id/status", auth, adminLimit, can("users:write"), handlers.UpdateUserStatus)
+	app.Post("/api/v1/admin/users/:id/unlock", auth, adminLimit, can("users:write"), handlers.UnlockUser)
+	app.Delete("/api/v1/admin/users/:id/mfa", auth, adminLimit, can("users:mfa_reset"), handlers.ResetUserMFA)
+	app.Delete("/api/v1/admin/users/:id", auth, adminLimit, can("users:delete"), handlers.DeleteUser)
+
+	app.Get("/api/v1/admin/audit-logs", auth, adminLimit, can("audit_logs:read"), handlers.ListAuditLogs)
+
+	return app
+}
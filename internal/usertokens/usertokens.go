@@ -0,0 +1,96 @@
+package usertokens
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"auth-service/internal/db"
+	"auth-service/internal/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Purposes of single-use tokens sent to users by email
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposePasswordReset = "password_reset"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Execer is satisfied by the pool and by a transaction, so a token can be
+// issued in the same transaction that queues the email carrying it
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// Querier is satisfied by the pool and by a transaction, so a token can be
+// consumed in the same transaction as the change it authorizes
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Issue creates a single-use token for userID and returns the raw value.
+// Earlier unused tokens for the same purpose stop working.
+func Issue(ctx context.Context, q Execer, userID int, purpose string, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := invalidate(ctx, q, userID, purpose); err != nil {
+		return "", err
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, NOW());
+	`, userID, purpose, utils.HashToken(token), time.Now().UTC().Add(ttl))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Peek returns the user of a live token without using it up
+func Peek(ctx context.Context, token, purpose string) (int, error) {
+	var userID int
+	err := db.DB.QueryRow(ctx, `
+		SELECT user_id FROM user_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3;
+	`, utils.HashToken(token), purpose, time.Now().UTC()).Scan(&userID)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return userID, nil
+}
+
+// Consume marks a live token used and returns its user. A token can be
+// consumed only once, even by concurrent requests.
+func Consume(ctx context.Context, q Querier, token, purpose string) (int, error) {
+	var userID int
+	err := q.QueryRow(ctx, `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+		RETURNING user_id;
+	`, utils.HashToken(token), purpose, time.Now().UTC()).Scan(&userID)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return userID, nil
+}
+
+// Invalidate burns every unused token of a purpose for userID
+func Invalidate(ctx context.Context, userID int, purpose string) error {
+	return invalidate(ctx, db.DB, userID, purpose)
+}
+
+func invalidate(ctx context.Context, q Execer, userID int, purpose string) error {
+	_, err := q.Exec(ctx, `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
+	`, userID, purpose)
+	return err
+}
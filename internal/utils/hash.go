@@ -1,23 +1,44 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"log"
 
-	"golang.org/x/crypto/bcrypt"
+	"auth-service/internal/passwordhash"
 )
 
-// HashPassword takes a plaintext password and returns its bcrypt hash
+// HashPassword takes a plaintext password and returns its hash in PHC format,
+// using the algorithm configured with passwordhash.SetDefault. Hashing runs on
+// the bounded hashing pool and fails with passwordhash.ErrOverloaded when it is full.
 func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := passwordhash.Hash(context.Background(), password)
 	if err != nil {
-		log.Printf("❌ Error hashing password: %v", err)
+		if !errors.Is(err, passwordhash.ErrOverloaded) {
+			log.Printf("❌ Error hashing password: %v", err)
+		}
 		return "", err
 	}
-	return string(hash), nil
+	return hash, nil
 }
 
-// CheckPassword compares a plaintext password with a hashed one
-func CheckPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+// CheckPassword compares a plaintext password with a hashed one. The error is
+// only set when the comparison could not be made, e.g. passwordhash.ErrOverloaded.
+func CheckPassword(password, hash string) (bool, error) {
+	ok, err := passwordhash.Verify(context.Background(), password, hash)
+	if err != nil {
+		if errors.Is(err, passwordhash.ErrOverloaded) {
+			return false, err
+		}
+		// A malformed stored hash simply never matches
+		log.Printf("❌ Error verifying password: %v", err)
+		return false, nil
+	}
+	return ok, nil
+}
+
+// NeedsRehash reports whether a stored hash should be replaced by a fresh one
+// because the algorithm, its parameters or the pepper have changed
+func NeedsRehash(hash string) bool {
+	return passwordhash.Default().NeedsRehash(hash)
 }
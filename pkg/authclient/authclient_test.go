@@ -0,0 +1,286 @@
+package authclient_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"auth-service/internal/db"
+	"auth-service/internal/server"
+	"auth-service/pkg/authclient"
+	jwtpkg "auth-service/pkg/jwt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// startService runs the real auth service routes in-process and returns its base URL
+func startService(t *testing.T) string {
+	t.Helper()
+
+	app := server.NewApp()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+
+	return "http://" + ln.Addr().String()
+}
+
+// useNewKey installs a freshly generated signing key and returns it
+func useNewKey(t *testing.T, alg string) *jwtpkg.SigningKey {
+	t.Helper()
+
+	key, _, err := jwtpkg.GenerateSigningKey(alg)
+	if err != nil {
+		t.Fatalf("generate %s key: %v", alg, err)
+	}
+	jwtpkg.SetSigningKey(key)
+	return key
+}
+
+func newVerifier(baseURL string) *authclient.Verifier {
+	return authclient.NewVerifier(authclient.VerifierConfig{
+		JWKSURL:            baseURL + "/.well-known/jwks.json",
+		Issuer:             jwtpkg.Settings().Issuer,
+		Audience:           jwtpkg.Settings().Audience,
+		MinRefreshInterval: time.Nanosecond,
+	})
+}
+
+func issue(t *testing.T, roles []string, audience string) string {
+	t.Helper()
+
+	token, err := jwtpkg.GenerateAccessToken(7, "user@example.com", roles, jwtpkg.TokenOptions{Audience: audience})
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	return token
+}
+
+func TestVerifierAcceptsServiceTokens(t *testing.T) {
+	baseURL := startService(t)
+
+	for _, alg := range []string{"RS256", "ES256", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			useNewKey(t, alg)
+			claims, err := newVerifier(baseURL).Verify(context.Background(), issue(t, []string{"admin"}, ""))
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if claims.UserID != 7 || claims.Email != "user@example.com" || !claims.HasRole("admin") {
+				t.Fatalf("unexpected claims: %+v", claims)
+			}
+		})
+	}
+}
+
+func TestVerifierRejectsOtherAudienceAndTampering(t *testing.T) {
+	baseURL := startService(t)
+	useNewKey(t, "ES256")
+	verifier := newVerifier(baseURL)
+
+	if _, err := verifier.Verify(context.Background(), issue(t, nil, "billing")); err == nil {
+		t.Fatal("token for another audience was accepted")
+	}
+
+	token := issue(t, nil, "")
+	tampered := token[:len(token)-4] + "AAAA"
+	if _, err := verifier.Verify(context.Background(), tampered); err == nil {
+		t.Fatal("tampered token was accepted")
+	}
+}
+
+func TestVerifierFollowsKeyRotation(t *testing.T) {
+	baseURL := startService(t)
+	verifier := newVerifier(baseURL)
+
+	oldKey := useNewKey(t, "ES256")
+	oldToken := issue(t, nil, "")
+	if _, err := verifier.Verify(context.Background(), oldToken); err != nil {
+		t.Fatalf("verify before rotation: %v", err)
+	}
+
+	// Promote a new key while the old one stays valid for verification
+	newKey, _, err := jwtpkg.GenerateSigningKey("EdDSA")
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	oldKey.ActivatesAt = time.Now().Add(-time.Hour)
+	newKey.ActivatesAt = time.Now()
+	jwtpkg.SetKeyRing(jwtpkg.NewKeyRing(oldKey, newKey))
+
+	if _, err := verifier.Verify(context.Background(), issue(t, nil, "")); err != nil {
+		t.Fatalf("verify token signed by rotated key: %v", err)
+	}
+	if _, err := verifier.Verify(context.Background(), oldToken); err != nil {
+		t.Fatalf("verify token signed before rotation: %v", err)
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	baseURL := startService(t)
+	useNewKey(t, "ES256")
+	verifier := newVerifier(baseURL)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := authclient.ClaimsFromContext(r.Context())
+		if claims == nil || claims.UserID != 7 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := verifier.Middleware(authclient.RequireRole("admin")(ok))
+
+	cases := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"garbage token", "Bearer nope", http.StatusUnauthorized},
+		{"missing role", "Bearer " + issue(t, []string{"user"}, ""), http.StatusForbidden},
+		{"admin", "Bearer " + issue(t, []string{"admin"}, ""), http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("got status %d, want %d", rec.Code, tc.want)
+			}
+		})
+	}
+}
+
+func TestFiberMiddleware(t *testing.T) {
+	baseURL := startService(t)
+	useNewKey(t, "ES256")
+	verifier := newVerifier(baseURL)
+
+	app := fiber.New()
+	app.Get("/admin", verifier.FiberMiddleware(), authclient.FiberRequireRole("admin"), func(c *fiber.Ctx) error {
+		claims := c.Locals("user").(*authclient.Claims)
+		return c.JSON(fiber.Map{"id": claims.UserID})
+	})
+
+	cases := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"no token", "", fiber.StatusUnauthorized},
+		{"missing role", "Bearer " + issue(t, []string{"user"}, ""), fiber.StatusForbidden},
+		{"admin", "Bearer " + issue(t, []string{"admin"}, ""), fiber.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			if resp.StatusCode != tc.want {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, tc.want)
+			}
+		})
+	}
+}
+
+// TestClientAgainstService exercises the REST client end to end. It needs a
+// scratch PostgreSQL database: AUTH_TEST_DATABASE_URL=postgres://... go test ./pkg/authclient
+func TestClientAgainstService(t *testing.T) {
+	databaseURL := os.Getenv("AUTH_TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("AUTH_TEST_DATABASE_URL not set")
+	}
+
+	t.Setenv("DATABASE_URL", databaseURL)
+	t.Setenv("SUPERADMIN_EMAIL", "superadmin@authclient.test")
+	t.Setenv("SUPERADMIN_PASSWORD", "authclient-test-password")
+	db.ConnectDB()
+	t.Cleanup(db.CloseDB)
+	db.RunMigrations("../../scripts/migrations")
+	db.SeedInitialData()
+
+	useNewKey(t, "ES256")
+	baseURL := startService(t)
+	client := authclient.New(baseURL)
+	ctx := context.Background()
+
+	login, err := client.Login(ctx, "superadmin@authclient.test", "authclient-test-password", "")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if _, err := client.Login(ctx, "superadmin@authclient.test", "wrong", ""); err == nil {
+		t.Fatal("login with wrong password succeeded")
+	}
+
+	refreshed, err := client.Refresh(ctx, login.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	info, err := client.Introspect(ctx, login.AccessToken, login.AccessToken)
+	if err != nil {
+		t.Fatalf("introspect: %v", err)
+	}
+	if !info.Active || info.Email != "superadmin@authclient.test" {
+		t.Fatalf("unexpected introspection: %+v", info)
+	}
+
+	decision, err := client.Authorize(ctx, refreshed.AccessToken, authclient.AuthorizeRequest{Action: "read", Resource: "users"})
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if !decision.Allowed {
+		t.Fatalf("super admin denied users:read: %s", decision.Reason)
+	}
+
+	verifier := newVerifier(baseURL)
+	protected := verifier.Middleware(authclient.RequirePermission(client, "users:read")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }),
+	))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+login.AccessToken)
+	rec := httptest.NewRecorder()
+	protected.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("RequirePermission returned %d", rec.Code)
+	}
+
+	// Replaying a rotated refresh token revokes the whole session family, so
+	// this runs last and checks from a separate session
+	if _, err := client.Refresh(ctx, login.RefreshToken); err == nil {
+		t.Fatal("reusing a rotated refresh token succeeded")
+	}
+	other, err := client.Login(ctx, "superadmin@authclient.test", "authclient-test-password", "")
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	for name, token := range map[string]string{"original": login.AccessToken, "refreshed": refreshed.AccessToken} {
+		info, err := client.Introspect(ctx, other.AccessToken, token)
+		if err != nil {
+			t.Fatalf("introspect %s token: %v", name, err)
+		}
+		if info.Active {
+			t.Fatalf("%s access token still active after refresh token reuse", name)
+		}
+	}
+	if _, err := client.Refresh(ctx, refreshed.RefreshToken); err == nil {
+		t.Fatal("refresh token of a revoked family still works")
+	}
+}
@@ -0,0 +1,175 @@
+package authclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client is a typed client for the auth service REST API
+type Client struct {
+	// BaseURL is the service root, e.g. http://auth:8080
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// New creates a Client for the service at baseURL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// APIError is returned for any non-2xx response
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("auth service returned %d: %s", e.StatusCode, e.Message)
+}
+
+// User is the user summary returned by Login
+type User struct {
+	ID     int      `json:"id"`
+	Email  string   `json:"email"`
+	Roles  []string `json:"roles"`
+	Status string   `json:"status"`
+}
+
+// TokenResponse is returned by Login and Refresh
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	User         *User  `json:"user,omitempty"`
+}
+
+// Introspection is an RFC 7662 introspection response
+type Introspection struct {
+	Active    bool     `json:"active"`
+	Sub       string   `json:"sub"`
+	Username  string   `json:"username"`
+	Email     string   `json:"email"`
+	Roles     []string `json:"roles"`
+	TokenType string   `json:"token_type"`
+	Exp       int64    `json:"exp"`
+	Iat       int64    `json:"iat"`
+	Nbf       int64    `json:"nbf"`
+	Iss       string   `json:"iss"`
+	Aud       []string `json:"aud"`
+	Jti       string   `json:"jti"`
+	Sid       string   `json:"sid"`
+}
+
+// AuthorizeRequest asks whether the user behind Token (or UserID) may perform
+// Action on Resource. With neither set, the caller itself is checked.
+type AuthorizeRequest struct {
+	Token    string `json:"token,omitempty"`
+	UserID   int    `json:"user_id,omitempty"`
+	Action   string `json:"action"`
+	Resource string `json:"resource"`
+}
+
+// Check is one entry of a batch authorization request
+type Check struct {
+	Action   string `json:"action"`
+	Resource string `json:"resource"`
+}
+
+// Decision is the service's allow/deny answer
+type Decision struct {
+	UserID     int    `json:"user_id,omitempty"`
+	Action     string `json:"action"`
+	Resource   string `json:"resource"`
+	Permission string `json:"permission"`
+	Allowed    bool   `json:"allowed"`
+	Reason     string `json:"reason"`
+}
+
+// Login exchanges credentials for an access and refresh token. audience may
+// be empty to get a token for the auth service itself.
+func (c *Client) Login(ctx context.Context, email, password, audience string) (*TokenResponse, error) {
+	var resp TokenResponse
+	body := map[string]string{"email": email, "password": password}
+	if audience != "" {
+		body["audience"] = audience
+	}
+	err := c.do(ctx, http.MethodPost, "/api/v1/login", "", body, &resp)
+	return &resp, err
+}
+
+// Refresh rotates a refresh token. The old token must not be used again:
+// replaying it revokes the whole session.
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*TokenResponse, error) {
+	var resp TokenResponse
+	err := c.do(ctx, http.MethodPost, "/api/v1/refresh", "", map[string]string{"refresh_token": refreshToken}, &resp)
+	return &resp, err
+}
+
+// Introspect asks the service whether token is active, authenticating with accessToken
+func (c *Client) Introspect(ctx context.Context, accessToken, token string) (*Introspection, error) {
+	var resp Introspection
+	err := c.do(ctx, http.MethodPost, "/api/v1/introspect", accessToken, map[string]string{"token": token}, &resp)
+	return &resp, err
+}
+
+// Authorize asks for a single allow/deny decision, authenticating with accessToken
+func (c *Client) Authorize(ctx context.Context, accessToken string, req AuthorizeRequest) (*Decision, error) {
+	var resp Decision
+	err := c.do(ctx, http.MethodPost, "/api/v1/authorize", accessToken, req, &resp)
+	return &resp, err
+}
+
+// AuthorizeBatch asks several questions about the same user in one call
+func (c *Client) AuthorizeBatch(ctx context.Context, accessToken, token string, userID int, checks []Check) ([]Decision, error) {
+	var resp struct {
+		Results []Decision `json:"results"`
+	}
+	body := map[string]any{"checks": checks}
+	if token != "" {
+		body["token"] = token
+	}
+	if userID != 0 {
+		body["user_id"] = userID
+	}
+	err := c.do(ctx, http.MethodPost, "/api/v1/authorize/batch", accessToken, body, &resp)
+	return resp.Results, err
+}
+
+func (c *Client) do(ctx context.Context, method, path, accessToken string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return &APIError{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,64 @@
+package authclient
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// FiberMiddleware rejects requests without a valid bearer token and stores
+// the claims in c.Locals("user") and the raw token in c.Locals("token")
+func (v *Verifier) FiberMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, ok := bearerToken(c.Get("Authorization"))
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Missing or invalid Authorization header",
+			})
+		}
+
+		claims, err := v.Verify(c.UserContext(), token)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid or expired token",
+			})
+		}
+
+		c.Locals("user", claims)
+		c.Locals("token", token)
+		return c.Next()
+	}
+}
+
+// FiberRequireRole allows only tokens carrying role. Must run after FiberMiddleware.
+func FiberRequireRole(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(*Claims)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+		if !claims.HasRole(role) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Missing role: " + role})
+		}
+		return c.Next()
+	}
+}
+
+// FiberRequirePermission asks the auth service whether the caller holds a
+// "resource:action" permission. Must run after FiberMiddleware.
+func FiberRequirePermission(client *Client, permission string) fiber.Handler {
+	resource, action := splitPermission(permission)
+	return func(c *fiber.Ctx) error {
+		token, ok := c.Locals("token").(string)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+
+		decision, err := client.Authorize(c.UserContext(), token, AuthorizeRequest{Action: action, Resource: resource})
+		if err != nil {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Failed to verify permissions"})
+		}
+		if !decision.Allowed {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Missing permission: " + permission})
+		}
+		return c.Next()
+	}
+}
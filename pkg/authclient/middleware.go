@@ -0,0 +1,116 @@
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type contextKey int
+
+const (
+	claimsKey contextKey = iota
+	tokenKey
+)
+
+// ClaimsFromContext returns the claims stored by Verifier.Middleware
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey).(*Claims)
+	return claims, ok
+}
+
+// TokenFromContext returns the raw bearer token stored by Verifier.Middleware
+func TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey).(string)
+	return token, ok
+}
+
+// Middleware is net/http middleware that rejects requests without a valid
+// bearer token and stores the claims in the request context
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r.Header.Get("Authorization"))
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "Missing or invalid Authorization header")
+			return
+		}
+
+		claims, err := v.Verify(r.Context(), token)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), claimsKey, claims)
+		ctx = context.WithValue(ctx, tokenKey, token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireRole allows only tokens carrying role. Must run after Verifier.Middleware.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				writeError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+			if !claims.HasRole(role) {
+				writeError(w, http.StatusForbidden, "Missing role: "+role)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission asks the auth service whether the caller holds a
+// "resource:action" permission. Permissions are not part of the token, so
+// this costs one call to /authorize (cached briefly by the service).
+// Must run after Verifier.Middleware.
+func RequirePermission(client *Client, permission string) func(http.Handler) http.Handler {
+	resource, action := splitPermission(permission)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := TokenFromContext(r.Context())
+			if !ok {
+				writeError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+
+			decision, err := client.Authorize(r.Context(), token, AuthorizeRequest{Action: action, Resource: resource})
+			if err != nil {
+				writeError(w, http.StatusBadGateway, "Failed to verify permissions")
+				return
+			}
+			if !decision.Allowed {
+				writeError(w, http.StatusForbidden, "Missing permission: "+permission)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bearerToken extracts the token from "Bearer <token>"
+func bearerToken(header string) (string, bool) {
+	parts := strings.Split(header, " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// splitPermission turns "users:write" into resource "users" and action "write"
+func splitPermission(permission string) (string, string) {
+	resource, action, _ := strings.Cut(permission, ":")
+	return resource, action
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,280 @@
+package authclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims mirrors the access token payload issued by the auth service
+type Claims struct {
+	UserID    int      `json:"sub"`
+	Email     string   `json:"email"`
+	Roles     []string `json:"roles"`
+	SessionID string   `json:"sid,omitempty"`
+	// Metadata carries the user metadata keys the auth service is configured
+	// to project, grouped by part. "user" values are set by the user
+	// themselves; authorize on "admin" values only.
+	Metadata map[string]any `json:"metadata,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// HasRole reports whether the token carries the role (roles are already
+// expanded through the role hierarchy by the auth service)
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// passwordChangeAudience mirrors jwt.PasswordChangeAudience in the service
+const passwordChangeAudience = "urn:auth-service:password-change"
+
+// VerifierConfig configures a Verifier
+type VerifierConfig struct {
+	// JWKSURL is the auth service's /.well-known/jwks.json
+	JWKSURL string
+	// Issuer must match the token's iss (the auth service's JWT_ISSUER)
+	Issuer string
+	// Audience must be contained in the token's aud (your service's name)
+	Audience string
+	// CacheTTL is how long fetched keys are trusted before refetching (default 5m)
+	CacheTTL time.Duration
+	// MinRefreshInterval limits refetches triggered by unknown kids (default 10s)
+	MinRefreshInterval time.Duration
+	// Leeway tolerates clock skew when checking exp / nbf / iat (default 30s)
+	Leeway time.Duration
+	// HTTPClient defaults to a client with a 10s timeout
+	HTTPClient *http.Client
+}
+
+// Verifier verifies access tokens locally against the auth service's JWKS.
+// Keys are cached and refetched when a token carries an unknown kid, so
+// signing key rotations are picked up without restarts.
+type Verifier struct {
+	cfg VerifierConfig
+
+	mu          sync.RWMutex
+	keys        map[string]jwkKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+type jwkKey struct {
+	alg string
+	key any
+}
+
+// NewVerifier creates a Verifier; keys are fetched lazily on first use
+func NewVerifier(cfg VerifierConfig) *Verifier {
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = 5 * time.Minute
+	}
+	if cfg.MinRefreshInterval <= 0 {
+		cfg.MinRefreshInterval = 10 * time.Second
+	}
+	if cfg.Leeway <= 0 {
+		cfg.Leeway = 30 * time.Second
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Verifier{cfg: cfg, keys: map[string]jwkKey{}}
+}
+
+// Verify checks signature, iss, aud, exp, nbf and iat and returns the claims.
+// It does not know about server-side revocation; use Client.Introspect for that.
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(v.cfg.Leeway),
+	}
+	if v.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.cfg.Issuer))
+	}
+	if v.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.cfg.Audience))
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := v.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.alg != "" && key.alg != token.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.key, nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid or expired token")
+	}
+	// Issued instead of a session when the user's password has expired; it
+	// must not pass as a login even for verifiers that accept any audience
+	if slices.Contains(claims.Audience, passwordChangeAudience) {
+		return nil, errors.New("token is only valid for changing the password")
+	}
+	return claims, nil
+}
+
+// key returns the cached key for kid, refreshing the JWKS when it is stale
+// or does not know the kid
+func (v *Verifier) key(ctx context.Context, kid string) (jwkKey, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	fresh := time.Since(v.fetchedAt) < v.cfg.CacheTTL
+	v.mu.RUnlock()
+	if ok && fresh {
+		return key, nil
+	}
+
+	if err := v.refresh(ctx); err != nil {
+		if ok {
+			// Serve the stale key rather than failing while the JWKS is unreachable
+			return key, nil
+		}
+		return jwkKey{}, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return jwkKey{}, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (v *Verifier) refresh(ctx context.Context) error {
+	// Stops tokens with bogus kids from hammering the JWKS endpoint
+	v.mu.Lock()
+	if time.Since(v.lastAttempt) < v.cfg.MinRefreshInterval && time.Since(v.fetchedAt) < v.cfg.CacheTTL {
+		v.mu.Unlock()
+		return nil
+	}
+	v.lastAttempt = time.Now()
+	v.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.JWKSURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]jwkKey, len(set.Keys))
+	for _, k := range set.Keys {
+		pub, err := k.publicKey()
+		if err != nil {
+			continue // skip key types we cannot use rather than failing the whole set
+		}
+		keys[k.Kid] = jwkKey{alg: k.Alg, key: pub}
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (any, error) {
+	b64 := base64.RawURLEncoding.DecodeString
+
+	switch k.Kty {
+	case "RSA":
+		n, err := b64(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
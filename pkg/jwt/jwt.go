@@ -1,44 +1,162 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
-	"os"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
-
 // CustomClaims defines our JWT payload structure
 type CustomClaims struct {
-	UserID int      `json:"sub"`
-	Email  string   `json:"email"`
-	Roles  []string `json:"roles"`
+	UserID    int      `json:"sub"`
+	Email     string   `json:"email"`
+	Roles     []string `json:"roles"`
+	SessionID string   `json:"sid,omitempty"`
+	// Metadata holds the user metadata keys selected by the
+	// jwt_metadata_claims policy, grouped by part ("user" / "admin")
+	Metadata map[string]any `json:"metadata,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// PasswordChangeAudience is the audience of the restricted token issued when
+// a password has expired. Services require their own audience, so the token
+// is only good for the change-password endpoint.
+const PasswordChangeAudience = "urn:auth-service:password-change"
+
+// ErrRestrictedToken is returned for a password-change token where a regular
+// access token is required
+var ErrRestrictedToken = errors.New("token is restricted to changing the password")
+
+// Config controls the registered claims put on and required from tokens
+type Config struct {
+	// Issuer is set as iss and required on every validated token
+	Issuer string
+	// Audience identifies this service: it is the default aud of new tokens
+	// and the audience ValidateToken requires
+	Audience string
+	// AccessTokenTTL is used when a token is generated without a TTL
+	AccessTokenTTL time.Duration
+	// Leeway tolerates clock skew between services when checking exp / nbf / iat
+	Leeway time.Duration
+}
+
+var cfg = Config{
+	Issuer:         "auth-service",
+	Audience:       "auth-service",
+	AccessTokenTTL: time.Hour,
+	Leeway:         30 * time.Second,
+}
+
+// Configure replaces the token configuration; call it once at startup
+func Configure(c Config) {
+	cfg = c
+}
+
+// Settings returns the current token configuration
+func Settings() Config {
+	return cfg
+}
+
+// TokenOptions customizes a single access token
+type TokenOptions struct {
+	// SessionID ties the token to the refresh token family it was issued with
+	SessionID string
+	// Audience defaults to Config.Audience
+	Audience string
+	// TTL defaults to Config.AccessTokenTTL
+	TTL time.Duration
+	// Metadata is copied into the metadata claim when non-empty
+	Metadata map[string]any
+}
+
 // GenerateAccessToken creates a new signed JWT for a user
-func GenerateAccessToken(userID int, email string, roles []string) (string, error) {
-	expiry := time.Now().Add(time.Hour * 1) // 1 hour by default
+func GenerateAccessToken(userID int, email string, roles []string, opts TokenOptions) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	audience := opts.Audience
+	if audience == "" {
+		audience = cfg.Audience
+	}
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = cfg.AccessTokenTTL
+	}
+
+	now := time.Now()
 	claims := CustomClaims{
-		UserID: userID,
-		Email:  email,
-		Roles:  roles,
+		UserID:    userID,
+		Email:     email,
+		Roles:     roles,
+		SessionID: opts.SessionID,
+		Metadata:  opts.Metadata,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiry),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			Issuer:    cfg.Issuer,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+
+	ring, err := currentKeyRing()
+	if err != nil {
+		return "", err
+	}
+	key, err := ring.SigningKey(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.Private)
 }
 
-// ValidateToken parses and validates a JWT string
+// ValidateToken parses and validates a JWT string issued for this service
 func ValidateToken(tokenString string) (*CustomClaims, error) {
+	return ValidateTokenForAudience(tokenString, cfg.Audience)
+}
+
+// ValidateTokenForAudience validates signature, iss, exp, nbf and iat, and
+// requires the given audience. An empty audience accepts any audience.
+func ValidateTokenForAudience(tokenString, audience string) (*CustomClaims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+
+	ring, err := currentKeyRing()
+	if err != nil {
+		return nil, err
+	}
+
+	// Pick the verification key by kid so tokens survive a key rotation
 	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, err := ring.VerificationKey(kid, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.Public, nil
+	}, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -50,3 +168,25 @@ func ValidateToken(tokenString string) (*CustomClaims, error) {
 
 	return claims, nil
 }
+
+// ValidateServiceToken validates a token minted for any service audience.
+// Password-change tokens are rejected with ErrRestrictedToken.
+func ValidateServiceToken(tokenString string) (*CustomClaims, error) {
+	claims, err := ValidateTokenForAudience(tokenString, "")
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(claims.Audience, PasswordChangeAudience) {
+		return nil, ErrRestrictedToken
+	}
+	return claims, nil
+}
+
+// newTokenID returns a random identifier for the jti claim
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
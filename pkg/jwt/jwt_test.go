@@ -0,0 +1,175 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// setup configures a test issuer and audience and installs ring
+func setup(t *testing.T, ring *KeyRing) {
+	t.Helper()
+	prev := Settings()
+	Configure(Config{Issuer: "test-issuer", Audience: "test-service", AccessTokenTTL: time.Hour, Leeway: 5 * time.Second})
+	SetKeyRing(ring)
+	t.Cleanup(func() { Configure(prev) })
+}
+
+func newKey(t *testing.T) *SigningKey {
+	t.Helper()
+	key, _, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// sign signs claims with key, bypassing GenerateAccessToken's defaults
+func sign(t *testing.T, key *SigningKey, claims CustomClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	s, err := token.SignedString(key.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// claimsAt returns valid claims for the test configuration, issued at now
+func claimsAt(now time.Time) CustomClaims {
+	return CustomClaims{
+		UserID: 7,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "test-issuer",
+			Audience:  jwt.ClaimStrings{"test-service"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+}
+
+func TestValidateTokenRegisteredClaims(t *testing.T) {
+	key := newKey(t)
+	setup(t, NewKeyRing(key))
+	now := time.Now()
+
+	claims, err := ValidateToken(sign(t, key, claimsAt(now)))
+	if err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if claims.UserID != 7 {
+		t.Fatalf("sub = %d", claims.UserID)
+	}
+
+	for name, mutate := range map[string]func(*CustomClaims){
+		"wrong iss":   func(c *CustomClaims) { c.Issuer = "someone-else" },
+		"no iss":      func(c *CustomClaims) { c.Issuer = "" },
+		"wrong aud":   func(c *CustomClaims) { c.Audience = jwt.ClaimStrings{"billing"} },
+		"future nbf":  func(c *CustomClaims) { c.NotBefore = jwt.NewNumericDate(now.Add(time.Hour)) },
+		"future iat":  func(c *CustomClaims) { c.IssuedAt = jwt.NewNumericDate(now.Add(time.Hour)) },
+		"expired":     func(c *CustomClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute)) },
+		"missing exp": func(c *CustomClaims) { c.ExpiresAt = nil },
+	} {
+		c := claimsAt(now)
+		mutate(&c)
+		if _, err := ValidateToken(sign(t, key, c)); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+
+	// Within the leeway, clock skew is tolerated
+	c := claimsAt(now)
+	c.NotBefore = jwt.NewNumericDate(now.Add(2 * time.Second))
+	if _, err := ValidateToken(sign(t, key, c)); err != nil {
+		t.Errorf("nbf within leeway rejected: %v", err)
+	}
+
+	// Another audience is only accepted when asked for
+	c = claimsAt(now)
+	c.Audience = jwt.ClaimStrings{"billing"}
+	if _, err := ValidateTokenForAudience(sign(t, key, c), "billing"); err != nil {
+		t.Errorf("billing token rejected for billing: %v", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	now := time.Now()
+	retiring, active := newKey(t), newKey(t)
+	retiring.ActivatesAt, retiring.RetiresAt = now.Add(-48*time.Hour), now.Add(time.Hour)
+	active.ActivatesAt = now.Add(-time.Hour)
+	setup(t, NewKeyRing(retiring, active))
+
+	// New tokens are signed with the most recently activated key
+	fresh, err := GenerateAccessToken(7, "a@example.com", nil, TokenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(fresh, &CustomClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := parsed.Header["kid"]; kid != active.ID {
+		t.Fatalf("signed with kid %v, want the active key %s", kid, active.ID)
+	}
+	if _, err := ValidateToken(fresh); err != nil {
+		t.Fatalf("fresh token rejected: %v", err)
+	}
+
+	// Tokens from before the rotation verify with the retiring key until it retires
+	old := sign(t, retiring, claimsAt(now))
+	if _, err := ValidateToken(old); err != nil {
+		t.Fatalf("token of the retiring key rejected: %v", err)
+	}
+
+	// The kid selects the key: a token claiming the wrong kid fails its signature
+	forged := jwt.NewWithClaims(retiring.Method, claimsAt(now))
+	forged.Header["kid"] = active.ID
+	forgedString, _ := forged.SignedString(retiring.Private)
+	if _, err := ValidateToken(forgedString); err == nil {
+		t.Error("token verified with the key of another kid")
+	}
+	unknown := jwt.NewWithClaims(retiring.Method, claimsAt(now))
+	unknown.Header["kid"] = "no-such-key"
+	unknownString, _ := unknown.SignedString(retiring.Private)
+	if _, err := ValidateToken(unknownString); err == nil {
+		t.Error("token with an unknown kid accepted")
+	}
+
+	// Once retired, the key no longer verifies anything
+	retiring.RetiresAt = now.Add(-time.Second)
+	SetKeyRing(NewKeyRing(retiring, active))
+	if _, err := ValidateToken(old); err == nil {
+		t.Error("token signed by a retired key accepted")
+	}
+	if _, err := ValidateToken(fresh); err != nil {
+		t.Errorf("token of the active key rejected after retirement: %v", err)
+	}
+}
+
+func TestPasswordChangeAudienceIsRestricted(t *testing.T) {
+	key := newKey(t)
+	setup(t, NewKeyRing(key))
+
+	restricted, err := GenerateAccessToken(7, "a@example.com", nil, TokenOptions{Audience: PasswordChangeAudience})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(restricted); err == nil {
+		t.Error("ValidateToken accepted a password-change token")
+	}
+	if _, err := ValidateServiceToken(restricted); !errors.Is(err, ErrRestrictedToken) {
+		t.Errorf("ValidateServiceToken = %v, want ErrRestrictedToken", err)
+	}
+	if _, err := ValidateTokenForAudience(restricted, PasswordChangeAudience); err != nil {
+		t.Errorf("password-change token rejected for its own audience: %v", err)
+	}
+
+	regular, _ := GenerateAccessToken(7, "a@example.com", nil, TokenOptions{})
+	if _, err := ValidateServiceToken(regular); err != nil {
+		t.Errorf("ValidateServiceToken rejected a regular token: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package jwt
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// KeyRing holds every key that may currently sign or verify tokens.
+// The signing key is the most recently activated, unretired key with a
+// private part; every unretired key stays valid for verification so tokens
+// issued before a rotation keep working until their key is retired.
+type KeyRing struct {
+	keys []*SigningKey
+}
+
+// NewKeyRing builds a key ring from the given keys
+func NewKeyRing(keys ...*SigningKey) *KeyRing {
+	sorted := append([]*SigningKey(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActivatesAt.After(sorted[j].ActivatesAt)
+	})
+	return &KeyRing{keys: sorted}
+}
+
+// Keys returns all keys in the ring, newest activation first
+func (r *KeyRing) Keys() []*SigningKey {
+	return append([]*SigningKey(nil), r.keys...)
+}
+
+// SigningKey returns the key that should sign new tokens at the given time
+func (r *KeyRing) SigningKey(now time.Time) (*SigningKey, error) {
+	for _, k := range r.keys {
+		if k.Private != nil && k.IsActive(now) {
+			return k, nil
+		}
+	}
+	return nil, errors.New("no active signing key")
+}
+
+// VerificationKey returns the unretired key with the given kid
+func (r *KeyRing) VerificationKey(kid string, now time.Time) (*SigningKey, error) {
+	for _, k := range r.keys {
+		if k.ID == kid {
+			if k.IsRetired(now) {
+				return nil, errors.New("signing key has been retired")
+			}
+			return k, nil
+		}
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+// PublicJWKS returns the public part of every unretired key, including keys
+// scheduled for future activation so consumers can cache them ahead of time
+func (r *KeyRing) PublicJWKS(now time.Time) JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, k := range r.keys {
+		if k.IsRetired(now) {
+			continue
+		}
+		if jwk, err := k.JWK(); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
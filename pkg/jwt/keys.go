@@ -0,0 +1,229 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a key used to sign and/or verify tokens.
+// For HS256 Private and Public both hold the shared secret.
+// A zero ActivatesAt means always active, a zero RetiresAt means never retired.
+type SigningKey struct {
+	ID          string
+	Method      jwt.SigningMethod
+	Private     any
+	Public      any
+	ActivatesAt time.Time
+	RetiresAt   time.Time
+}
+
+// IsRetired reports whether the key may no longer verify tokens
+func (k *SigningKey) IsRetired(now time.Time) bool {
+	return !k.RetiresAt.IsZero() && !now.Before(k.RetiresAt)
+}
+
+// IsActive reports whether the key may sign tokens
+func (k *SigningKey) IsActive(now time.Time) bool {
+	return !now.Before(k.ActivatesAt) && !k.IsRetired(now)
+}
+
+var (
+	keysMu     sync.RWMutex
+	keyRing    *KeyRing
+	keysLoaded bool
+)
+
+// LoadKeysFromEnv installs the key configured in the environment as the only key
+func LoadKeysFromEnv() error {
+	key, err := KeyFromEnv()
+	if err != nil {
+		return err
+	}
+	if key.Method == jwt.SigningMethodHS256 {
+		log.Println("⚠️  JWT_PRIVATE_KEY_PATH not set, signing tokens with shared HS256 secret")
+	}
+
+	SetSigningKey(key)
+	return nil
+}
+
+// KeyFromEnv reads the statically configured key.
+// JWT_PRIVATE_KEY_PATH selects an RSA, ECDSA or Ed25519 PEM key (JWT_KEY_ID
+// optionally overrides its kid); otherwise JWT_SECRET is used with HS256.
+func KeyFromEnv() (*SigningKey, error) {
+	if path := os.Getenv("JWT_PRIVATE_KEY_PATH"); path != "" {
+		return LoadPrivateKeyPEM(path, os.Getenv("JWT_KEY_ID"))
+	}
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, errors.New("neither JWT_PRIVATE_KEY_PATH nor JWT_SECRET is set")
+	}
+	return &SigningKey{Method: jwt.SigningMethodHS256, Private: []byte(secret), Public: []byte(secret)}, nil
+}
+
+// SetSigningKey replaces the key ring with a single, never-rotating key
+func SetSigningKey(key *SigningKey) {
+	SetKeyRing(NewKeyRing(key))
+}
+
+// SetKeyRing replaces the keys used to sign and verify tokens
+func SetKeyRing(ring *KeyRing) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	keyRing = ring
+	keysLoaded = true
+}
+
+// currentKeyRing returns the key ring, loading it from the environment on first use
+func currentKeyRing() (*KeyRing, error) {
+	keysMu.RLock()
+	ring, loaded := keyRing, keysLoaded
+	keysMu.RUnlock()
+	if loaded {
+		return ring, nil
+	}
+
+	if err := LoadKeysFromEnv(); err != nil {
+		return nil, err
+	}
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	return keyRing, nil
+}
+
+// LoadPrivateKeyPEM reads a PKCS#8, PKCS#1 or SEC 1 private key from disk.
+// When kid is empty the RFC 7638 thumbprint of the public key is used.
+func LoadPrivateKeyPEM(path, kid string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key: %w", err)
+	}
+
+	key, err := ParsePrivateKeyPEM(data, kid)
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// ParsePrivateKeyPEM decodes a PEM-encoded private key
+func ParsePrivateKeyPEM(data []byte, kid string) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	priv, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSigningKey(priv, kid)
+}
+
+// ErrUnsupportedAlgorithm is returned by GenerateSigningKey for an algorithm
+// it cannot create keys for
+var ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+// GenerateSigningKey creates a fresh private key for the given JWS algorithm
+// (RS256, ES256, ES384 or EdDSA) and returns it with its PKCS#8 PEM encoding
+func GenerateSigningKey(alg string) (*SigningKey, []byte, error) {
+	var priv crypto.Signer
+	var err error
+	switch alg {
+	case "RS256":
+		priv, err = rsa.GenerateKey(rand.Reader, 3072)
+	case "ES256":
+		priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		priv, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "EdDSA":
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, nil, fmt.Errorf("%w %q", ErrUnsupportedAlgorithm, alg)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := NewSigningKey(priv, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// NewSigningKey wraps an asymmetric private key, picking the JWS algorithm
+// from the key type
+func NewSigningKey(priv crypto.Signer, kid string) (*SigningKey, error) {
+	var method jwt.SigningMethod
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA signing keys must be at least 2048 bits")
+		}
+		method = jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			method = jwt.SigningMethodES256
+		case elliptic.P384():
+			method = jwt.SigningMethodES384
+		case elliptic.P521():
+			method = jwt.SigningMethodES512
+		default:
+			return nil, errors.New("unsupported ECDSA curve")
+		}
+	case ed25519.PrivateKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", priv)
+	}
+
+	key := &SigningKey{ID: kid, Method: method, Private: priv, Public: priv.Public()}
+	if key.ID == "" {
+		thumbprint, err := key.Thumbprint()
+		if err != nil {
+			return nil, err
+		}
+		key.ID = thumbprint
+	}
+	return key, nil
+}
+
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("PKCS#8 key is not a signing key")
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unsupported private key format")
+}
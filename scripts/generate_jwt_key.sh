@@ -0,0 +1,19 @@
+#!/usr/bin/env sh
+# Generates a JWT signing key for JWT_PRIVATE_KEY_PATH.
+# Usage: scripts/generate_jwt_key.sh [es256|rs256|eddsa] [output.pem]
+set -e
+
+ALG="${1:-es256}"
+OUT="${2:-keys/jwt_signing.pem}"
+
+mkdir -p "$(dirname "$OUT")"
+
+case "$ALG" in
+  es256) openssl genpkey -algorithm EC -pkeyopt ec_paramgen_curve:P-256 -out "$OUT" ;;
+  rs256) openssl genpkey -algorithm RSA -pkeyopt rsa_keygen_bits:3072 -out "$OUT" ;;
+  eddsa) openssl genpkey -algorithm ed25519 -out "$OUT" ;;
+  *) echo "unknown algorithm: $ALG" >&2; exit 1 ;;
+esac
+
+chmod 600 "$OUT"
+echo "✅ Wrote $ALG signing key to $OUT"
@@ -0,0 +1,22 @@
+-- ==========================================
+-- Migration: 003_refresh_token_rotation.sql
+-- Purpose: Support hashed, rotating refresh tokens with reuse detection
+-- ==========================================
+
+-- `token` now stores the SHA-256 hash of the refresh token, never the raw value.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id TEXT;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS replaced_by INT REFERENCES refresh_tokens(id) ON DELETE SET NULL;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP;
+
+-- Any tokens written before this migration have no family and cannot be rotated
+UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW() WHERE family_id IS NULL AND revoked = FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '003_refresh_token_rotation.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '003_refresh_token_rotation.sql'
+);
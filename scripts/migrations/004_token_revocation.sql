@@ -0,0 +1,24 @@
+-- ==========================================
+-- Migration: 004_token_revocation.sql
+-- Purpose: Server-side revocation of access tokens (logout / logout everywhere)
+-- ==========================================
+
+-- Individually revoked access tokens, kept only until they would have expired
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti TEXT PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+-- Any access token issued at or before this instant is rejected
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMP;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '004_token_revocation.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '004_token_revocation.sql'
+);
@@ -0,0 +1,21 @@
+-- ==========================================
+-- Migration: 005_signing_keys.sql
+-- Purpose: Key ring for JWT signing key rotation
+-- ==========================================
+
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id SERIAL PRIMARY KEY,
+    kid TEXT UNIQUE NOT NULL,
+    algorithm TEXT NOT NULL,
+    private_key TEXT NOT NULL,           -- PKCS#8 PEM, AES-GCM encrypted with JWT_KEYSTORE_SECRET
+    activates_at TIMESTAMP NOT NULL,     -- starts signing (newest active key wins)
+    retires_at TIMESTAMP,                -- stops verifying; NULL = never
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '005_signing_keys.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '005_signing_keys.sql'
+);
@@ -0,0 +1,21 @@
+-- ==========================================
+-- Migration: 006_token_audience_and_lifetimes.sql
+-- Purpose: Per-audience tokens and policy-driven token lifetimes
+-- ==========================================
+
+-- Audience the session's access tokens are minted for (NULL = this service)
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS audience TEXT;
+
+INSERT INTO auth_policies (name, value)
+VALUES
+  ('allowed_audiences', '[]'),
+  ('access_token_ttl', '{"default":"1h"}'),
+  ('refresh_token_ttl', '"168h"')
+ON CONFLICT (name) DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '006_token_audience_and_lifetimes.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '006_token_audience_and_lifetimes.sql'
+);
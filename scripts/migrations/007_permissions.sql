@@ -0,0 +1,65 @@
+-- ==========================================
+-- Migration: 007_permissions.sql
+-- Purpose: Fine-grained permissions granted through roles
+-- ==========================================
+
+-- =========================
+-- PERMISSIONS TABLE
+-- =========================
+-- Names are "resource:action"; "*" and "resource:*" are wildcards
+CREATE TABLE IF NOT EXISTS permissions (
+    id SERIAL PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    description TEXT
+);
+
+-- =========================
+-- ROLE_PERMISSIONS (Many-to-Many)
+-- =========================
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id INT NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+-- Built-in roles
+INSERT INTO roles (name, description)
+VALUES
+  ('super_admin', 'Has all system permissions'),
+  ('admin', 'Manages users, limited config'),
+  ('user', 'Basic access only'),
+  ('service', 'Used by other backend services')
+ON CONFLICT (name) DO NOTHING;
+
+-- Permissions guarding the built-in endpoints
+INSERT INTO permissions (name, description)
+VALUES
+  ('*', 'Every permission'),
+  ('users:read', 'List and view users'),
+  ('users:write', 'Activate or deactivate users'),
+  ('users:delete', 'Permanently delete users'),
+  ('roles:read', 'List roles'),
+  ('roles:write', 'Create roles'),
+  ('roles:assign', 'Assign and revoke user roles'),
+  ('permissions:read', 'List permissions and role grants'),
+  ('permissions:write', 'Manage permissions and role grants'),
+  ('policies:read', 'View auth policies'),
+  ('policies:write', 'Change auth policies'),
+  ('keys:manage', 'Generate, promote and retire signing keys')
+ON CONFLICT (name) DO NOTHING;
+
+-- Preserve the previous hardcoded access rules
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON
+     (r.name = 'super_admin' AND p.name = '*')
+  OR (r.name = 'admin' AND p.name IN ('users:read', 'users:write'))
+ON CONFLICT DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '007_permissions.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '007_permissions.sql'
+);
@@ -0,0 +1,30 @@
+-- ==========================================
+-- Migration: 008_role_hierarchy.sql
+-- Purpose: Role inheritance (SUPER_ADMIN → ADMIN → USER / SERVICE)
+-- ==========================================
+
+-- role_id inherits every permission (and role membership) of parent_id
+CREATE TABLE IF NOT EXISTS role_parents (
+    role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    parent_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, parent_id),
+    CHECK (role_id <> parent_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_parents_parent_id ON role_parents (parent_id);
+
+-- Default hierarchy from docs/idea.yaml
+INSERT INTO role_parents (role_id, parent_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN roles p ON
+     (r.name = 'super_admin' AND p.name = 'admin')
+  OR (r.name = 'admin' AND p.name IN ('user', 'service'))
+ON CONFLICT DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '008_role_hierarchy.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '008_role_hierarchy.sql'
+);
@@ -0,0 +1,22 @@
+-- ==========================================
+-- Migration: 009_authorization_api.sql
+-- Purpose: Let backend services ask authorization questions about other users
+-- ==========================================
+
+INSERT INTO permissions (name, description)
+VALUES ('authz:check', 'Query /authorize about users other than yourself')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'authz:check'
+WHERE r.name = 'service'
+ON CONFLICT DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '009_authorization_api.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '009_authorization_api.sql'
+);
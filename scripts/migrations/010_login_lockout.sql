@@ -0,0 +1,30 @@
+-- ==========================================
+-- Migration: 010_login_lockout.sql
+-- Purpose: Track failed logins per account and per IP for lockouts and backoff
+-- ==========================================
+
+-- key is 'email:<address>' or 'ip:<address>'; unknown emails are tracked too
+CREATE TABLE IF NOT EXISTS login_failures (
+    key TEXT PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_last_failure_at ON login_failures(last_failure_at);
+
+INSERT INTO auth_policies (name, value)
+VALUES
+  ('max_failed_logins', '5'),
+  ('max_failed_logins_per_ip', '50'),
+  ('lockout_duration', '"15m"'),
+  ('login_backoff_threshold', '3'),
+  ('login_backoff_base', '"1s"')
+ON CONFLICT (name) DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '010_login_lockout.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '010_login_lockout.sql'
+);
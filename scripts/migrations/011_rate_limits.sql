@@ -0,0 +1,30 @@
+-- ==========================================
+-- Migration: 011_rate_limits.sql
+-- Purpose: Shared rate limit state for RATE_LIMIT_STORE=postgres
+-- ==========================================
+
+-- One row per rule + key; token bucket uses tokens/updated_at,
+-- sliding window uses window_start/prev_count/curr_count
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP,
+    window_start TIMESTAMP,
+    prev_count INT NOT NULL DEFAULT 0,
+    curr_count INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limits_expires_at ON rate_limits(expires_at);
+
+-- Per-rule overrides, e.g. {"login-ip": {"algorithm": "sliding_window", "limit": 20, "window": "1m"}}
+INSERT INTO auth_policies (name, value)
+VALUES ('rate_limits', '{}')
+ON CONFLICT (name) DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '011_rate_limits.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '011_rate_limits.sql'
+);
@@ -0,0 +1,48 @@
+-- ==========================================
+-- Migration: 012_mfa.sql
+-- Purpose: TOTP multi-factor authentication, recovery codes and login challenges
+-- ==========================================
+
+-- secret is AES-GCM encrypted; enabled_at stays NULL until the first code is confirmed
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMP,
+    last_used_step BIGINT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
+
+-- Pending logins that passed the password check (token is stored hashed)
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL,
+    purpose TEXT NOT NULL,
+    audience TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- mfa_required_roles: e.g. '["super_admin"]' forces MFA for super admins
+INSERT INTO auth_policies (name, value)
+VALUES
+  ('mfa_required_roles', '[]'),
+  ('mfa_challenge_ttl', '"5m"')
+ON CONFLICT (name) DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '012_mfa.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '012_mfa.sql'
+);
@@ -0,0 +1,40 @@
+-- ==========================================
+-- Migration: 013_webauthn.sql
+-- Purpose: WebAuthn / passkey credentials and ceremony sessions
+-- ==========================================
+
+-- Random user handle given to authenticators instead of the numeric id
+ALTER TABLE users ADD COLUMN IF NOT EXISTS webauthn_handle BYTEA UNIQUE;
+
+-- credential holds the verified credential record (public key, flags, AAGUID);
+-- sign_count is authoritative and must increase on every assertion
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id BYTEA UNIQUE NOT NULL,
+    name TEXT NOT NULL,
+    credential JSONB NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT NOW(),
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+
+-- Pending registration / login ceremonies (single use, token stored hashed)
+CREATE TABLE IF NOT EXISTS webauthn_sessions (
+    id SERIAL PRIMARY KEY,
+    token_hash TEXT UNIQUE NOT NULL,
+    ceremony TEXT NOT NULL,
+    user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    data JSONB NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '013_webauthn.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '013_webauthn.sql'
+);
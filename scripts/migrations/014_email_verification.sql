@@ -0,0 +1,36 @@
+-- ==========================================
+-- Migration: 014_email_verification.sql
+-- Purpose: Email verification and single-use user tokens
+-- ==========================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+-- Accounts created before verification existed are treated as verified.
+-- Guarded so re-running the file never verifies newer accounts.
+UPDATE users SET email_verified_at = COALESCE(created_at, NOW())
+WHERE email_verified_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM schema_migrations WHERE name = '014_email_verification.sql');
+
+-- Single-use tokens mailed to users (verification, password reset); stored hashed
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user_purpose ON user_tokens(user_id, purpose);
+
+INSERT INTO auth_policies (name, value)
+VALUES ('email_verification_ttl', '"24h"')
+ON CONFLICT (name) DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '014_email_verification.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '014_email_verification.sql'
+);
@@ -0,0 +1,15 @@
+-- ==========================================
+-- Migration: 015_password_reset.sql
+-- Purpose: Password reset token lifetime (tokens live in user_tokens)
+-- ==========================================
+
+INSERT INTO auth_policies (name, value)
+VALUES ('password_reset_ttl', '"1h"')
+ON CONFLICT (name) DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '015_password_reset.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '015_password_reset.sql'
+);
@@ -0,0 +1,24 @@
+-- ==========================================
+-- Migration: 017_password_policy.sql
+-- Purpose: Password strength rules applied wherever a password is set
+-- ==========================================
+
+-- password_min_strength is a zxcvbn-style score from 0 (off) to 4
+INSERT INTO auth_policies (name, value)
+VALUES
+  ('password_min_length', '8'),
+  ('password_max_length', '64'),
+  ('password_require_uppercase', 'false'),
+  ('password_require_lowercase', 'false'),
+  ('password_require_digit', 'false'),
+  ('password_require_symbol', 'false'),
+  ('password_forbid_email', 'true'),
+  ('password_min_strength', '2')
+ON CONFLICT (name) DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '017_password_policy.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '017_password_policy.sql'
+);
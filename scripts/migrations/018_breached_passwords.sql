@@ -0,0 +1,15 @@
+-- ==========================================
+-- Migration: 018_breached_passwords.sql
+-- Purpose: Reject passwords found in the local breach list (BREACHED_PASSWORDS_FILE)
+-- ==========================================
+
+INSERT INTO auth_policies (name, value)
+VALUES ('password_forbid_breached', 'true')
+ON CONFLICT (name) DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '018_breached_passwords.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '018_breached_passwords.sql'
+);
@@ -0,0 +1,37 @@
+-- ==========================================
+-- Migration: 019_password_history.sql
+-- Purpose: Password reuse prevention and maximum password age
+-- ==========================================
+
+-- Existing accounts start their password age at this migration
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+CREATE TABLE IF NOT EXISTS password_history (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history(user_id, created_at DESC);
+
+-- Current passwords count as the first history entry (first run only)
+INSERT INTO password_history (user_id, password_hash, created_at)
+SELECT id, password_hash, password_changed_at FROM users
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '019_password_history.sql'
+);
+
+-- password_max_age_days = 0 disables expiry
+INSERT INTO auth_policies (name, value)
+VALUES
+  ('password_history_count', '5'),
+  ('password_max_age_days', '0')
+ON CONFLICT (name) DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '019_password_history.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '019_password_history.sql'
+);
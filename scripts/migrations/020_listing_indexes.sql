@@ -0,0 +1,54 @@
+-- ==========================================
+-- Migration: 020_listing_indexes.sql
+-- Purpose: Indexes behind paginated admin listings (users, roles, audit logs)
+-- ==========================================
+
+-- Keyset pagination compares (sort column, id); sort columns must be NOT NULL
+UPDATE users SET created_at = NOW() WHERE created_at IS NULL;
+ALTER TABLE users ALTER COLUMN created_at SET NOT NULL;
+
+UPDATE audit_logs SET created_at = NOW() WHERE created_at IS NULL;
+ALTER TABLE audit_logs ALTER COLUMN created_at SET NOT NULL;
+
+-- Users: sort by created_at, email prefix search, filter by role
+-- (sorting by id or email uses the primary key / unique email index)
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_users_email_lower_pattern ON users(lower(email) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles(role_id, user_id);
+
+-- Roles: name prefix search
+CREATE INDEX IF NOT EXISTS idx_roles_name_lower_pattern ON roles(lower(name) text_pattern_ops);
+
+-- Audit logs: newest first, optionally per user or per action
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at_id ON audit_logs(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action, created_at);
+
+-- Substring search (email_contains) needs pg_trgm; without it the filter
+-- still works, just with a sequential scan
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION WHEN OTHERS THEN
+    RAISE NOTICE 'pg_trgm unavailable (%), email_contains will not be indexed', SQLERRM;
+END
+$$;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        CREATE INDEX IF NOT EXISTS idx_users_email_lower_trgm ON users USING gin (lower(email) gin_trgm_ops);
+    END IF;
+END
+$$;
+
+INSERT INTO permissions (name, description)
+VALUES ('audit_logs:read', 'View audit logs')
+ON CONFLICT (name) DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '020_listing_indexes.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '020_listing_indexes.sql'
+);
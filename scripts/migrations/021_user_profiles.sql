@@ -0,0 +1,25 @@
+-- ==========================================
+-- Migration: 021_user_profiles.sql
+-- Purpose: Profile fields and user / admin metadata on users
+-- ==========================================
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS display_name TEXT,
+    ADD COLUMN IF NOT EXISTS locale TEXT,
+    ADD COLUMN IF NOT EXISTS timezone TEXT,
+    ADD COLUMN IF NOT EXISTS avatar_url TEXT,
+    -- "user" is editable by the user, "admin" only by admins
+    ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{"user": {}, "admin": {}}';
+
+-- jwt_metadata_claims: metadata keys copied into access tokens,
+-- e.g. '["admin.tenant", "user.theme"]'
+INSERT INTO auth_policies (name, value)
+VALUES ('jwt_metadata_claims', '[]')
+ON CONFLICT (name) DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '021_user_profiles.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '021_user_profiles.sql'
+);
@@ -0,0 +1,15 @@
+-- ==========================================
+-- Migration: 022_email_outbox_scrub.sql
+-- Purpose: Drop bodies of delivered / abandoned mail (they hold reset and verification links)
+-- ==========================================
+
+UPDATE email_outbox
+SET text_body = '', html_body = NULL
+WHERE status IN ('sent', 'failed') AND (text_body <> '' OR html_body IS NOT NULL);
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '022_email_outbox_scrub.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '022_email_outbox_scrub.sql'
+);
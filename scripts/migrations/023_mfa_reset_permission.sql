@@ -0,0 +1,17 @@
+-- ==========================================
+-- Migration: 023_mfa_reset_permission.sql
+-- Purpose: Separate permission for removing another user's MFA enrollment
+-- ==========================================
+
+-- Not granted to admin: stripping a second factor is an account takeover
+-- step, so it is given out explicitly (super_admin holds it through "*")
+INSERT INTO permissions (name, description)
+VALUES ('users:mfa_reset', 'Remove a user''s MFA enrollment')
+ON CONFLICT (name) DO NOTHING;
+
+-- Record this migration as applied (idempotent)
+INSERT INTO schema_migrations (name)
+SELECT '023_mfa_reset_permission.sql'
+WHERE NOT EXISTS (
+    SELECT 1 FROM schema_migrations WHERE name = '023_mfa_reset_permission.sql'
+);